@@ -0,0 +1,131 @@
+package main
+
+import (
+	"did/standard"
+	"encoding/json"
+	"errors"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+// ContractConfig 合约配置，保存在链上，可在安装、升级合约时设置，也可由管理员通过SetConfig修改
+type ContractConfig struct {
+	// EnableTrustIssuer 验证VC时是否要求发行者在信任发行者列表中
+	EnableTrustIssuer bool `json:"enableTrustIssuer"`
+	// EnableVcIssueLog 验证VC时是否要求VC在签发日志中
+	EnableVcIssueLog bool `json:"enableVcIssueLog"`
+	// MaxPageSize 分页查询每页最大条数
+	MaxPageSize int `json:"maxPageSize"`
+	// PageTotal 分页查询是否统计总数，统计总数需要遍历所有符合条件的数据
+	PageTotal bool `json:"pageTotal"`
+	// ProofTypes 允许的证明类型，为空表示不限制
+	ProofTypes []string `json:"proofTypes"`
+	// MaxDocumentSize DID文档最大字节数，0表示不限制
+	MaxDocumentSize int `json:"maxDocumentSize"`
+	// ClockSkew 验证有效期时允许的时钟偏差，单位秒
+	ClockSkew int64 `json:"clockSkew"`
+	// Domain 中继提交的签名所属的域，一般为链ID和合约名称，例如"chain1/DID"，未设置时不能执行需要中继签名的操作
+	Domain string `json:"domain"`
+}
+
+// defaultConfig 未设置配置时使用的默认配置
+func defaultConfig() *ContractConfig {
+	return &ContractConfig{
+		EnableTrustIssuer: true,
+		EnableVcIssueLog:  true,
+		MaxPageSize:       defaultSearchCount,
+	}
+}
+
+func (c *ContractConfig) validate() error {
+	if c.MaxPageSize <= 0 {
+		return errors.New("invalid config, maxPageSize must be greater than 0")
+	}
+	if c.MaxDocumentSize < 0 {
+		return errors.New("invalid config, maxDocumentSize must not be negative")
+	}
+	if c.ClockSkew < 0 {
+		return errors.New("invalid config, clockSkew must not be negative")
+	}
+	for _, proofType := range c.ProofTypes {
+		if len(proofType) == 0 {
+			return errors.New("invalid config, empty proof type")
+		}
+	}
+	return nil
+}
+
+// IsProofTypeAllowed 判断证明类型是否允许
+func (c *ContractConfig) IsProofTypeAllowed(proofType string) bool {
+	if len(c.ProofTypes) == 0 {
+		return true
+	}
+	return isInList(proofType, c.ProofTypes)
+}
+
+// mergeConfig 将json中出现的字段合并到当前配置中，未出现的字段保持不变
+func (e *DidContract) mergeConfig(configJson string) (*ContractConfig, error) {
+	config := e.dal.getConfig()
+	err := json.Unmarshal([]byte(configJson), config)
+	if err != nil {
+		return nil, errors.New("invalid config json, " + err.Error())
+	}
+	err = config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// InitConfig 安装、升级合约时设置合约配置
+func (e *DidContract) InitConfig(configJson string) error {
+	config, err := e.mergeConfig(configJson)
+	if err != nil {
+		return err
+	}
+	err = e.dal.putConfig(config)
+	if err != nil {
+		return err
+	}
+	e.EmitSetConfigEvent(config)
+	return nil
+}
+
+// SetConfig 修改合约配置，只需要传入要修改的字段
+func (e *DidContract) SetConfig(configJson string) error {
+	if !e.isAdmin() {
+		return errors.New("only admin can set config")
+	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
+	return e.InitConfig(configJson)
+}
+
+// GetConfig 获取合约配置
+func (e *DidContract) GetConfig() (*ContractConfig, error) {
+	return e.dal.getConfig(), nil
+}
+
+// EmitSetConfigEvent 发送修改合约配置事件
+func (e *DidContract) EmitSetConfigEvent(config *ContractConfig) {
+	configJson, _ := json.Marshal(config)
+	sdk.Instance.EmitEvent(standard.Topic_SetConfig, []string{string(configJson)})
+}
+
+// checkProofType 检查证明类型是否允许
+func (e *DidContract) checkProofType(proofType string) error {
+	if !e.dal.getConfig().IsProofTypeAllowed(proofType) {
+		return errors.New("proof type not allowed: " + proofType)
+	}
+	return nil
+}
+
+// checkDocumentSize 检查DID文档大小
+func (e *DidContract) checkDocumentSize(didDocument string) error {
+	maxSize := e.dal.getConfig().MaxDocumentSize
+	if maxSize > 0 && len(didDocument) > maxSize {
+		return errors.New("did document is too large")
+	}
+	return nil
+}
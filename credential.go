@@ -0,0 +1,90 @@
+package main
+
+import (
+	"did/standard"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var vcStatuses = []string{standard.VcStatusActive, standard.VcStatusRevoked, standard.VcStatusExpired}
+
+// GetHolderCredentials 获取持有人的vc列表，每个vcID一条，状态由撤销记录和过期时间得出
+// @param issuer 选填，按发行者过滤
+// @param templateId 选填，按模板ID过滤
+// @param status 选填，按状态过滤，active、revoked、expired
+func (e *DidContract) GetHolderCredentials(did string, issuer string, templateId string, status string,
+	cursor string, count int) (*standard.Page[*standard.HolderCredential], error) {
+	if len(did) == 0 {
+		return nil, errors.New("did is empty")
+	}
+	if len(status) != 0 && !isInList(status, vcStatuses) {
+		return nil, errors.New("invalid vc status: " + status)
+	}
+	p, err := newPager[*standard.HolderCredential](e.dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return nil, err
+	}
+	vcIssueLogs, err := e.dal.getHolderVcIssueLogs(did)
+	if err != nil {
+		return nil, err
+	}
+	//同一个vcID多次签发时以最近一次为准
+	latest := make(map[string]*standard.VcIssueLog)
+	for _, vcIssueLog := range vcIssueLogs {
+		if l, ok := latest[vcIssueLog.VcID]; !ok || l.IssueTime < vcIssueLog.IssueTime {
+			latest[vcIssueLog.VcID] = vcIssueLog
+		}
+	}
+	//按发行时间倒序，保证分页稳定，排序位置同时作为游标
+	credentials := make([]*standard.HolderCredential, 0, len(latest))
+	positions := make(map[*standard.HolderCredential]string, len(latest))
+	for _, vcIssueLog := range latest {
+		credential := &standard.HolderCredential{
+			VcID:       vcIssueLog.VcID,
+			Issuer:     vcIssueLog.Issuer,
+			TemplateId: vcIssueLog.TemplateId,
+			IssueTime:  vcIssueLog.IssueTime,
+			Expiration: vcIssueLog.Expiration,
+		}
+		credentials = append(credentials, credential)
+		positions[credential] = fmt.Sprintf("%019d.%s", math.MaxInt64-credential.IssueTime, encodeKey(credential.VcID))
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return positions[credentials[i]] < positions[credentials[j]]
+	})
+	for _, credential := range credentials {
+		if p.done() {
+			break
+		}
+		if len(issuer) != 0 && credential.Issuer != issuer {
+			continue
+		}
+		if len(templateId) != 0 && credential.TemplateId != templateId {
+			continue
+		}
+		credential.Status = e.vcStatus(credential, myTime)
+		if len(status) != 0 && credential.Status != status {
+			continue
+		}
+		p.seek(keyVcIssueLog, positions[credential])
+		p.add(credential)
+	}
+	return p.page(), nil
+}
+
+// vcStatus 计算vc当前状态，撤销优先于过期
+func (e *DidContract) vcStatus(credential *standard.HolderCredential, now int64) string {
+	if e.isInRevokeVcList(credential.VcID) {
+		return standard.VcStatusRevoked
+	}
+	if credential.Expiration != 0 && credential.Expiration <= now {
+		return standard.VcStatusExpired
+	}
+	return standard.VcStatusActive
+}
@@ -7,30 +7,60 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
 )
 
 const (
-	keyDid             = "d" // 此为存入数据库的世界状态key，故越短越好
+	keyDid             = "d2" // 此为存入数据库的世界状态key，故越短越好
 	keyIndexPubKey     = "p"
-	keyIndexAddress    = "a"
-	keyTrustIssuer     = "ti"
+	keyIndexAddress    = "a2"
+	keyTrustIssuer     = "ti2"
 	keyTrustRoot       = "tr"
-	keyRevokeVc        = "r"
-	keyBlackList       = "b"
-	keyDelegate        = "g"
-	keyVcTemplate      = "vt"
+	keyRevokeVc        = "r2"
+	keyBlackList       = "b2"
+	keyBlackListVm     = "bv2"
+	keyBlackListPubKey = "bk"
+	keyBlackListAddr   = "ba2"
+	keyDelegate        = "g2"
+	keyVcTemplate      = "vt2"
+	keyTemplateOwner   = "vto"
+	keyTemplateCompat  = "vtc"
+	// VcTemplate按类型、所有者的索引，值为模板在keyVcTemplate中的field
+	keyTemplateByType  = "vty"
+	keyTemplateByOwner = "vtw"
+	keyDidNonce        = "dn"
+	keyDidDeactivated  = "dx"
 	keyAdmin           = "Admin"
-	keyVcIssueLog      = "l"
-	keyVcIndexIssueLog = "vl"
+	keyAdminCouncil    = "Council"
+	keyAdminTransfer   = "AdminTransfer"
+	keyProposalSeq     = "ProposalSeq"
+	keyProposal        = "pp"
+	keyRole            = "role2"
+	keyPause           = "pause"
+	keyConfig          = "Config"
+	keySchema          = "Schema"
+	keyVcIssueLog      = "l2"
+	keyVcIndexIssueLog = "vl2"
+	// VcIssueLog按发行者、模板、VC内容哈希的索引，以及持有人的发行者列表
+	keyVcIssueLogByIssuer   = "li"
+	keyVcIssueLogByTemplate = "lt"
+	keyVcIssueLogByHash     = "lvh"
+	keyVcHolderIssuer       = "lhi"
+	// 授权按被授权者、授权链根授权者的索引，值为授权在keyDelegate中的field，keyDelegate本身按授权者排列
+	keyDelegateByDelegatee = "ge"
+	keyDelegateByRoot      = "gr"
+	// 链下签名授权已使用的nonce
+	keyDelegateNonce = "gn"
 )
 
 var (
 	errDidNotFound      = errors.New("did not found")
 	errTemplateNotFound = errors.New("template not found")
 	errDataNotFound     = errors.New("data not found")
+	errDidDeactivated   = errors.New("did is deactivated")
 )
 
 // Dal 数据库访问层
@@ -41,12 +71,79 @@ type Dal struct {
 func (dal *Dal) Db() sdk.SDKInterface {
 	return sdk.Instance
 }
-func processDid4Key(did string) string {
-	if len(did) > 9 {
-		did = did[9:] //去掉did:cnbn:
+
+// encodeKey 将任意字符串编码为可存入数据库的字段
+// 字母、数字和'-'保持不变，其他字节编码为'_'加两位十六进制，编码结果互不冲突，且保持前缀关系，可用于前缀查询
+func encodeKey(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// joinKey 编码多个字符串并用'.'连接为一个字段，'.'不会出现在编码结果中，所以拼接后也不会冲突
+func joinKey(parts ...string) string {
+	encoded := make([]string, len(parts))
+	for i, part := range parts {
+		encoded[i] = encodeKey(part)
 	}
-	return strings.ReplaceAll(did, ":", "_")
+	return strings.Join(encoded, ".")
 }
+
+// fieldLimit 范围遍历时字段的上界，链上字段只能包含字母、数字、'-'、'_'和'.'，
+// 本合约的字段都以编码后的DID、ID、哈希或者数字开头，不会出现连续64个'z'
+var fieldLimit = strings.Repeat("z", 64)
+
+// errStopIteration 遍历回调返回该错误时提前结束遍历，不作为错误返回
+var errStopIteration = errors.New("stop iteration")
+
+// fieldAfter 返回大于field的最小字段，'-'是字段中最小的字符
+func fieldAfter(field string) string {
+	return field + "-"
+}
+
+// iteratePrefix 遍历key下以prefix开头的所有field
+func (dal *Dal) iteratePrefix(key, prefix string, fn func(field string, value []byte) error) error {
+	iter, err := dal.Db().NewIteratorPrefixWithKeyField(key, prefix)
+	if err != nil {
+		return err
+	}
+	return iterate(iter, fn)
+}
+
+// iterateRange 按字段顺序遍历key下[startField, limitField)范围内的field，可用于从上次遍历到的位置继续
+func (dal *Dal) iterateRange(key, startField, limitField string, fn func(field string, value []byte) error) error {
+	iter, err := dal.Db().NewIteratorWithField(key, startField, limitField)
+	if err != nil {
+		return err
+	}
+	return iterate(iter, fn)
+}
+
+func iterate(iter sdk.ResultSetKV, fn func(field string, value []byte) error) error {
+	defer iter.Close()
+	for iter.HasNext() {
+		_, field, value, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		err = fn(field, value)
+		if err == errStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func processPubKey4Key(pubKey string) string {
 	hash := sha256.Sum256([]byte(pubKey))
 	return hex.EncodeToString(hash[:])
@@ -54,15 +151,47 @@ func processPubKey4Key(pubKey string) string {
 
 func (dal *Dal) putDidDocument(did string, didDocument []byte) error {
 	//将DID Document存入数据库
-	err := dal.Db().PutStateByte(keyDid, processDid4Key(did), didDocument)
+	err := dal.Db().PutStateByte(keyDid, encodeKey(did), didDocument)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// getDidNonce 获取DID已经使用的最大签名操作nonce，没有时为0
+func (dal *Dal) getDidNonce(did string) (int64, error) {
+	value, err := dal.Db().GetStateByte(keyDidNonce, encodeKey(did))
+	if err != nil {
+		return 0, err
+	}
+	if len(value) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+// putDidNonce 记录DID已经使用的签名操作nonce
+func (dal *Dal) putDidNonce(did string, nonce int64) error {
+	return dal.Db().PutStateByte(keyDidNonce, encodeKey(did), []byte(strconv.FormatInt(nonce, 10)))
+}
+
+// putDidDeactivated 记录DID的注销时间
+func (dal *Dal) putDidDeactivated(did string, deactivateTime int64) error {
+	return dal.Db().PutStateByte(keyDidDeactivated, encodeKey(did), []byte(strconv.FormatInt(deactivateTime, 10)))
+}
+
+// isDidDeactivated 判断DID是否已经注销
+func (dal *Dal) isDidDeactivated(did string) (bool, error) {
+	value, err := dal.Db().GetStateByte(keyDidDeactivated, encodeKey(did))
+	if err != nil {
+		return false, err
+	}
+	return len(value) != 0, nil
+}
+
 func (dal *Dal) getDidDocument(did string) ([]byte, error) {
 	//从数据库中获取DID Document
-	didDocument, err := dal.Db().GetStateByte(keyDid, processDid4Key(did))
+	didDocument, err := dal.getStateCompat(keyDid, encodeKey(did), legacyKeyDid, processDid4Key(did))
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +232,7 @@ func (dal *Dal) getDidByPubKey(pubKey string) (string, error) {
 
 func (dal *Dal) putIndexAddress(address string, did string) error {
 	//将索引存入数据库
-	err := dal.Db().PutStateByte(keyIndexAddress, address, []byte(did))
+	err := dal.Db().PutStateByte(keyIndexAddress, encodeKey(address), []byte(did))
 	if err != nil {
 		return err
 	}
@@ -112,7 +241,7 @@ func (dal *Dal) putIndexAddress(address string, did string) error {
 
 func (dal *Dal) deleteIndexAddress(address string) error {
 	//从数据库中删除索引
-	err := dal.Db().DelState(keyIndexAddress, address)
+	err := dal.delStateCompat(keyIndexAddress, encodeKey(address), legacyKeyIndexAddress, address)
 	if err != nil {
 		return err
 	}
@@ -121,7 +250,7 @@ func (dal *Dal) deleteIndexAddress(address string) error {
 
 func (dal *Dal) getDidByAddress(address string) (string, error) {
 	//从数据库中获取索引
-	did, err := dal.Db().GetStateByte(keyIndexAddress, address)
+	did, err := dal.getStateCompat(keyIndexAddress, encodeKey(address), legacyKeyIndexAddress, address)
 	if err != nil {
 		return "", err
 	}
@@ -154,7 +283,7 @@ func (dal *Dal) getTrustRootList() ([]string, error) {
 
 func (dal *Dal) putTrustIssuer(did string) error {
 	//将TrustIssuer存入数据库
-	err := dal.Db().PutStateByte(keyTrustIssuer, processDid4Key(did), []byte(did))
+	err := dal.Db().PutStateByte(keyTrustIssuer, encodeKey(did), []byte(did))
 	if err != nil {
 		return err
 	}
@@ -162,62 +291,43 @@ func (dal *Dal) putTrustIssuer(did string) error {
 }
 func (dal *Dal) getTrustIssuer(did string) (string, error) {
 	//从数据库中获取TrustIssuer
-	didUrl, err := dal.Db().GetStateByte(keyTrustIssuer, processDid4Key(did))
-	if err != nil || len(didUrl) == 0 {
+	didUrl, err := dal.getStateCompat(keyTrustIssuer, encodeKey(did), legacyKeyTrustIssuer, processDid4Key(did))
+	//旧数据的字段编码有冲突，需要检查存储的DID是否一致
+	if err != nil || string(didUrl) != did {
 		return "", errDataNotFound
 	}
 	return string(didUrl), nil
 }
 func (dal *Dal) deleteTrustIssuer(did string) error {
 	//从数据库中删除TrustIssuer
-	err := dal.Db().DelState(keyTrustIssuer, processDid4Key(did))
+	err := dal.delStateCompat(keyTrustIssuer, encodeKey(did), legacyKeyTrustIssuer, processDid4Key(did))
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (dal *Dal) searchTrustIssuer(didSearch string, start int, count int) ([]string, error) {
-	//从数据库中查询RevokeVc迭代器
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyTrustIssuer, processDid4Key(didSearch))
-	if err != nil {
+func (dal *Dal) searchTrustIssuer(didSearch string, cursor string, count int) (*standard.Page[string], error) {
+	if err := dal.checkMigrated(); err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var dids []string
-	i := 0
-	if count == 0 {
-		count = defaultSearchCount
+	p, err := newPager[string](dal, cursor, count)
+	if err != nil {
+		return nil, err
 	}
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
-		}
-		if i >= start+count {
-			break
-		}
-		i++
-		if i < start {
-			continue
-		}
-		dids = append(dids, string(value))
-
+	//从数据库中查询TrustIssuer迭代器
+	err = p.iterate(keyTrustIssuer, encodeKey(didSearch), func(_ string, value []byte) error {
+		p.add(string(value))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return dids, nil
-}
-
-func processVcId(vcID string) string {
-	//vcid 是一个http url，为了存入数据库，需要将其转换为一个只有字母大小写、数字、下划线的字符串
-	vcID = strings.ReplaceAll(vcID, ":", "_")
-	vcID = strings.ReplaceAll(vcID, "/", "_")
-	vcID = strings.ReplaceAll(vcID, ".", "_")
-	vcID = strings.ReplaceAll(vcID, "-", "_")
-	return vcID
+	return p.page(), nil
 }
 
 func (dal *Dal) putRevokeVc(vcID string) error {
 	//将RevokeVc存入数据库
-	err := dal.Db().PutStateByte(keyRevokeVc, processVcId(vcID), []byte(vcID))
+	err := dal.Db().PutStateByte(keyRevokeVc, encodeKey(vcID), []byte(vcID))
 	if err != nil {
 		return err
 	}
@@ -225,212 +335,514 @@ func (dal *Dal) putRevokeVc(vcID string) error {
 }
 func (dal *Dal) getRevokeVc(vcID string) (string, error) {
 	//从数据库中获取RevokeVc
-	vcIDUrl, err := dal.Db().GetStateByte(keyRevokeVc, processVcId(vcID))
-	if err != nil || len(vcIDUrl) == 0 {
+	vcIDUrl, err := dal.getStateCompat(keyRevokeVc, encodeKey(vcID), legacyKeyRevokeVc, processVcId(vcID))
+	//旧数据的字段编码有冲突，需要检查存储的vcID是否一致
+	if err != nil || string(vcIDUrl) != vcID {
 		return "", errDataNotFound
 	}
 	return string(vcIDUrl), nil
 }
 
-// searchRevokeVc 根据vcID前缀查询RevokeVc,start为起始位置从0开始，count为查询数量
-func (dal *Dal) searchRevokeVc(vcIDSearch string, start int, count int) ([]string, error) {
+// searchRevokeVc 根据vcID前缀分页查询RevokeVc
+func (dal *Dal) searchRevokeVc(vcIDSearch string, cursor string, count int) (*standard.Page[string], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	p, err := newPager[string](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
 	//从数据库中查询RevokeVc迭代器
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyRevokeVc, processVcId(vcIDSearch))
+	err = p.iterate(keyRevokeVc, encodeKey(vcIDSearch), func(_ string, value []byte) error {
+		p.add(string(value))
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var vcIDSlice []string
-	i := 0
-	if count == 0 {
-		count = defaultSearchCount
+	return p.page(), nil
+}
+
+// blackListKey 根据黑名单条目的类型返回对应的key和field
+// 条目可以是DID、验证方法ID、公钥PEM或者地址
+func blackListKey(item string) (string, string) {
+	switch {
+	case strings.HasPrefix(item, "-----BEGIN"):
+		return keyBlackListPubKey, processPubKey4Key(item)
+	case strings.HasPrefix(item, "did:") && strings.Contains(item, "#"):
+		return keyBlackListVm, encodeKey(item)
+	case strings.HasPrefix(item, "did:"):
+		return keyBlackList, encodeKey(item)
+	default:
+		return keyBlackListAddr, encodeKey(item)
 	}
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
-		}
-		if i >= start+count {
-			break
-		}
-		i++
-		if i < start {
-			continue
-		}
-		vcIDSlice = append(vcIDSlice, string(value))
+}
 
+// legacyBlackListKey 旧版本存储中黑名单条目对应的key和field
+func legacyBlackListKey(item string) (string, string) {
+	switch {
+	case strings.HasPrefix(item, "-----BEGIN"):
+		return keyBlackListPubKey, processPubKey4Key(item)
+	case strings.HasPrefix(item, "did:") && strings.Contains(item, "#"):
+		return legacyKeyBlackListVm, processVm4Key(item)
+	case strings.HasPrefix(item, "did:"):
+		return legacyKeyBlackList, processDid4Key(item)
+	default:
+		return legacyKeyBlackListAddr, item
 	}
-	return vcIDSlice, nil
 }
 
-func (dal *Dal) putBlackList(did string) error {
+// parseBlackListEntry 解析黑名单记录，兼容只存储了条目本身的旧数据
+func parseBlackListEntry(value []byte) *standard.BlackListEntry {
+	var entry standard.BlackListEntry
+	if len(value) > 0 && value[0] == '{' && json.Unmarshal(value, &entry) == nil {
+		return &entry
+	}
+	return &standard.BlackListEntry{Did: string(value)}
+}
+
+func (dal *Dal) putBlackList(entry *standard.BlackListEntry) error {
 	//将BlackList存入数据库
-	err := dal.Db().PutStateByte(keyBlackList, processDid4Key(did), []byte(did))
+	key, field := blackListKey(entry.Did)
+	value, _ := json.Marshal(entry)
+	err := dal.Db().PutStateByte(key, field, value)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (dal *Dal) isInBlackList(did string) bool {
+func (dal *Dal) getBlackList(item string) (*standard.BlackListEntry, error) {
 	//从数据库中获取BlackList
-	dbId, err := dal.Db().GetStateByte(keyBlackList, processDid4Key(did))
-	if err != nil || len(dbId) == 0 {
+	key, field := blackListKey(item)
+	legacyKey, legacyField := legacyBlackListKey(item)
+	value, err := dal.getStateCompat(key, field, legacyKey, legacyField)
+	if err != nil || len(value) == 0 {
+		return nil, errDataNotFound
+	}
+	return parseBlackListEntry(value), nil
+}
+func (dal *Dal) isInBlackList(item string) bool {
+	entry, err := dal.getBlackList(item)
+	if err != nil {
 		return false
 	}
+	//已过期的黑名单记录不再生效
+	if entry.Expiration != 0 {
+		myTime, err := getTxTime()
+		if err != nil || myTime >= entry.Expiration {
+			return false
+		}
+	}
 	return true
 }
-func (dal *Dal) deleteBlackList(did string) error {
+func (dal *Dal) deleteBlackList(item string) error {
 	//从数据库中删除BlackList
-	err := dal.Db().DelState(keyBlackList, processDid4Key(did))
+	key, field := blackListKey(item)
+	legacyKey, legacyField := legacyBlackListKey(item)
+	err := dal.delStateCompat(key, field, legacyKey, legacyField)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (dal *Dal) searchBlackList(didSearch string, start int, count int) ([]string, error) {
-	//从数据库中查询BlackList迭代器
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyBlackList, processDid4Key(didSearch))
+func (dal *Dal) searchBlackList(didSearch string, cursor string, count int) (
+	*standard.Page[*standard.BlackListEntry], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	p, err := newPager[*standard.BlackListEntry](dal, cursor, count)
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var didSlice []string
-	//  start 为起始位置从0开始，count为查询数量，如果count为0，则查询所有
-	//  从start开始，查询start后面count个，如果start为0则总数为count个；如果start为1开始，则总数为count+1个
-	//  例如这里传入start为1时，count为2时会返回第1、2、3个数据
-	//i := 0
-	//if count == 0 {
-	//	count = defaultSearchCount
-	//}
-	//for iter.HasNext() {
-	//	_, _, value, err1 := iter.Next()
-	//	if err1 != nil {
-	//		return nil, err1
-	//	}
-	//	if i >= start+count {
-	//		break
-	//	}
-	//	i++
-	//	if i < start {
-	//		continue
-	//	}
-	//	didSlice = append(didSlice, string(value))
-	//
-	//}
-
-	// start 为起始位置从0开始，count为查询数量，如果count为0，则查询所有
-	// 从start开始，查询总数为count个的数据
-	// 例如这里传入start为1时，count为2时会返回第1、2个数据
-	i := 0         // 用于追踪当前迭代到的项
-	collected := 0 // 用于追踪已收集的项的数量
-
-	if count == 0 {
-		count = defaultSearchCount
+	//依次查询DID、验证方法、公钥、地址黑名单，公钥和地址黑名单只在didSearch为空时返回
+	prefixes := [][2]string{
+		{keyBlackList, encodeKey(didSearch)},
+		{keyBlackListVm, encodeKey(didSearch)},
 	}
-
-	for iter.HasNext() {
-		if collected >= count {
-			break
-		}
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
-		}
-		i++
-		if i < start {
-			continue
+	if len(didSearch) == 0 {
+		prefixes = append(prefixes, [2]string{keyBlackListPubKey, ""}, [2]string{keyBlackListAddr, ""})
+	}
+	for _, prefix := range prefixes {
+		err = p.iterate(prefix[0], prefix[1], func(_ string, value []byte) error {
+			p.add(parseBlackListEntry(value))
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		didSlice = append(didSlice, string(value))
-		collected++
 	}
-	return didSlice, nil
+	return p.page(), nil
 }
 
 func (dal *Dal) putDelegate(d *standard.DelegateInfo) error {
 	//将Delegate存入数据库
 	value, _ := json.Marshal(d)
 
-	field := d.DelegatorDid + "_" + d.DelegateeDid + "_" + d.Resource + "_" + d.Action
-	err := dal.Db().PutStateByte(keyDelegate, processVcId(field), value)
+	field := delegateField(d)
+	err := dal.Db().PutStateByte(keyDelegate, field, value)
+	if err != nil {
+		return err
+	}
+	return dal.putDelegateIndex(d, field)
+}
+
+// putDelegateIndex 写入授权的被授权者索引和授权链索引
+func (dal *Dal) putDelegateIndex(d *standard.DelegateInfo, field string) error {
+	err := dal.Db().PutStateByte(keyDelegateByDelegatee, delegateeField(d), []byte(field))
 	if err != nil {
 		return err
 	}
+	if len(d.RootDelegator) != 0 {
+		return dal.Db().PutStateByte(keyDelegateByRoot, delegateRootField(d), []byte(field))
+	}
 	return nil
 }
-func (dal *Dal) searchDelegate(delegatorDid, delegateeDid, resource, action string, start int, count int) (
-	[]*standard.DelegateInfo, error) {
-	fieldPrefx := delegatorDid + "_"
+
+// isDelegateNonceUsed 判断授权者的nonce是否已经使用
+func (dal *Dal) isDelegateNonceUsed(delegatorDid string, nonce string) (bool, error) {
+	value, err := dal.Db().GetStateByte(keyDelegateNonce, joinKey(delegatorDid, nonce))
+	if err != nil {
+		return false, err
+	}
+	return len(value) != 0, nil
+}
+
+// putDelegateNonce 记录授权者已使用的nonce
+func (dal *Dal) putDelegateNonce(delegatorDid string, nonce string) error {
+	return dal.Db().PutStateByte(keyDelegateNonce, joinKey(delegatorDid, nonce), []byte("1"))
+}
+
+// delegateChainRoot 再委托授权的起点授权者，直接授权为空，使field与没有起点字段的旧授权一致
+func delegateChainRoot(d *standard.DelegateInfo) string {
+	if len(d.ParentDelegator) == 0 {
+		return ""
+	}
+	return d.RootDelegator
+}
+
+// delegateField 授权在keyDelegate中的field，包含上一级和起点授权者，
+// 直接授权和不同授权链上的再委托即使资源、操作相同也不会互相覆盖
+func delegateField(d *standard.DelegateInfo) string {
+	return joinKey(d.DelegatorDid, d.DelegateeDid, d.Resource, d.Action, d.ParentDelegator, delegateChainRoot(d))
+}
+
+// delegateeField 授权在keyDelegateByDelegatee索引中的field
+func delegateeField(d *standard.DelegateInfo) string {
+	return joinKey(d.DelegateeDid, d.DelegatorDid, d.Resource, d.Action, d.ParentDelegator, delegateChainRoot(d))
+}
+
+// delegateRootField 授权在keyDelegateByRoot索引中的field
+func delegateRootField(d *standard.DelegateInfo) string {
+	return joinKey(d.RootDelegator, d.DelegateeDid, d.DelegatorDid, d.Resource, d.Action, d.ParentDelegator)
+}
+
+// getDelegatesByRoot 获取授权链起点为rootDid、被授权者为delegateeDid的所有授权
+func (dal *Dal) getDelegatesByRoot(rootDid, delegateeDid string) ([]*standard.DelegateInfo, error) {
+	var delegates []*standard.DelegateInfo
+	err := dal.iteratePrefix(keyDelegateByRoot, joinKey(rootDid, delegateeDid)+".", func(_ string, field []byte) error {
+		value, err := dal.Db().GetStateByte(keyDelegate, string(field))
+		if err != nil {
+			return err
+		}
+		if len(value) == 0 {
+			return nil
+		}
+		var delegate standard.DelegateInfo
+		_ = json.Unmarshal(value, &delegate)
+		delegates = append(delegates, &delegate)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delegates, nil
+}
+
+// getDelegates 获取delegator对delegatee的所有授权
+func (dal *Dal) getDelegates(delegatorDid, delegateeDid string) ([]*standard.DelegateInfo, error) {
+	var delegates []*standard.DelegateInfo
+	fields := make(map[string]bool)
+	prefix := joinKey(delegatorDid, delegateeDid) + "."
+	err := dal.iteratePrefix(keyDelegate, prefix, func(field string, value []byte) error {
+		var delegate standard.DelegateInfo
+		_ = json.Unmarshal(value, &delegate)
+		delegates = append(delegates, &delegate)
+		fields[field] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	//迁移完成前还要读取旧表中的授权，新表中已有的授权优先
+	legacyPrefix := processVcId(delegatorDid + "_" + delegateeDid + "_")
+	err = dal.iterateLegacy(legacyKeyDelegate, legacyPrefix, func(value []byte) error {
+		var delegate standard.DelegateInfo
+		_ = json.Unmarshal(value, &delegate)
+		if delegate.DelegatorDid == delegatorDid && delegate.DelegateeDid == delegateeDid &&
+			!fields[delegateField(&delegate)] {
+			delegates = append(delegates, &delegate)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delegates, nil
+}
+
+func (dal *Dal) searchDelegate(delegatorDid, delegateeDid, resource, action string, cursor string, count int) (
+	*standard.Page[*standard.DelegateInfo], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	p, err := newPager[*standard.DelegateInfo](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	//前缀只包含完整的字段，最后一段按字段值精确过滤，避免资源a匹配到资源a.b、操作sign匹配到sign,verify
+	fieldPrefx := encodeKey(delegatorDid) + "."
 	if len(delegateeDid) != 0 {
-		fieldPrefx += delegateeDid + "_"
+		fieldPrefx += encodeKey(delegateeDid) + "."
 		if len(resource) != 0 {
-			fieldPrefx += resource + "_"
-			if len(action) != 0 {
-				fieldPrefx += action
-			}
+			fieldPrefx += encodeKey(resource) + "."
 		}
 	}
 	//从数据库中查询Delegate迭代器
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyDelegate, processVcId(fieldPrefx))
+	err = p.iterate(keyDelegate, fieldPrefx, func(_ string, value []byte) error {
+		var delegate standard.DelegateInfo
+		_ = json.Unmarshal(value, &delegate)
+		if delegate.DelegatorDid != delegatorDid ||
+			len(delegateeDid) != 0 && delegate.DelegateeDid != delegateeDid ||
+			len(resource) != 0 && delegate.Resource != resource ||
+			len(action) != 0 && delegate.Action != action {
+			return nil
+		}
+		p.add(&delegate)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var delegateSlice []*standard.DelegateInfo
-	i := 0
-	if count == 0 {
-		count = defaultSearchCount
+	return p.page(), nil
+}
+
+// getDelegationsReceived 按被授权者索引分页查询delegateeDid收到的授权
+func (dal *Dal) getDelegationsReceived(delegateeDid string, cursor string, count int) (
+	*standard.Page[*standard.DelegateInfo], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
 	}
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
+	p, err := newPager[*standard.DelegateInfo](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	err = p.iterate(keyDelegateByDelegatee, encodeKey(delegateeDid)+".", func(_ string, field []byte) error {
+		value, err1 := dal.Db().GetStateByte(keyDelegate, string(field))
 		if err1 != nil {
-			return nil, err1
+			return err1
 		}
-		if i >= start+count {
-			break
-		}
-		i++
-		if i < start {
-			continue
+		if len(value) == 0 {
+			return nil
 		}
 		var delegate standard.DelegateInfo
-		_ = json.Unmarshal([]byte(value), &delegate)
-		delegateSlice = append(delegateSlice, &delegate)
+		_ = json.Unmarshal(value, &delegate)
+		if delegate.DelegateeDid == delegateeDid {
+			p.add(&delegate)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return delegateSlice, nil
+	return p.page(), nil
 }
 
+// hasDelegateeIndex 判断授权是否已经有被授权者索引
+func (dal *Dal) hasDelegateeIndex(d *standard.DelegateInfo) (bool, error) {
+	value, err := dal.Db().GetStateByte(keyDelegateByDelegatee, delegateeField(d))
+	if err != nil {
+		return false, err
+	}
+	return len(value) != 0, nil
+}
+
+// revokeDelegate 撤销delegator给delegatee对resource、action的所有授权，包括直接授权和各授权链上的再委托
 func (dal *Dal) revokeDelegate(delegatorDid, delegateeDid string, resource string, action string) error {
-	//从数据库中删除Delegate
-	field := delegatorDid + "_" + delegateeDid + "_" + resource + "_" + action
+	var fields []string
+	var delegates []*standard.DelegateInfo
+	err := dal.iteratePrefix(keyDelegate, joinKey(delegatorDid, delegateeDid, resource, action)+".",
+		func(field string, value []byte) error {
+			var delegate standard.DelegateInfo
+			_ = json.Unmarshal(value, &delegate)
+			fields = append(fields, field)
+			delegates = append(delegates, &delegate)
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	//从数据库中删除Delegate及其索引
+	for i, delegate := range delegates {
+		if err = dal.Db().DelState(keyDelegateByDelegatee, delegateeField(delegate)); err != nil {
+			return err
+		}
+		if len(delegate.RootDelegator) != 0 {
+			if err = dal.Db().DelState(keyDelegateByRoot, delegateRootField(delegate)); err != nil {
+				return err
+			}
+		}
+		if err = dal.Db().DelState(keyDelegate, fields[i]); err != nil {
+			return err
+		}
+	}
+	//迁移完成前旧表中的授权也要删除
+	if !dal.isLegacySchema() {
+		return nil
+	}
+	legacyField := processVcId(delegatorDid + "_" + delegateeDid + "_" + resource + "_" + action)
+	return dal.Db().DelState(legacyKeyDelegate, legacyField)
+}
 
-	err := dal.Db().DelState(keyDelegate, processVcId(field))
+func (dal *Dal) putVcTemplate(vcTemplate *standard.VcTemplate) error {
+	field := joinKey(vcTemplate.Id, vcTemplate.Version)
+	//草稿修改后类型可能变化，先删除旧的索引
+	oldValue, err := dal.Db().GetStateByte(keyVcTemplate, field)
+	if err != nil {
+		return err
+	}
+	if len(oldValue) != 0 {
+		var old standard.VcTemplate
+		_ = json.Unmarshal(oldValue, &old)
+		if err = dal.deleteVcTemplateIndex(&old); err != nil {
+			return err
+		}
+	}
+	//将VcTemplate存入数据库
+	value, _ := json.Marshal(vcTemplate)
+	err = dal.Db().PutStateByte(keyVcTemplate, field, value)
 	if err != nil {
 		return err
 	}
+	return dal.putVcTemplateIndex(vcTemplate)
+}
+
+// putVcTemplateIndex 保存VcTemplate按类型、所有者的索引
+func (dal *Dal) putVcTemplateIndex(vcTemplate *standard.VcTemplate) error {
+	field := []byte(joinKey(vcTemplate.Id, vcTemplate.Version))
+	err := dal.Db().PutStateByte(keyTemplateByType, joinKey(vcTemplate.VcType, vcTemplate.Id, vcTemplate.Version),
+		field)
+	if err != nil {
+		return err
+	}
+	if len(vcTemplate.Owner) != 0 {
+		err = dal.Db().PutStateByte(keyTemplateByOwner, joinKey(vcTemplate.Owner, vcTemplate.Id, vcTemplate.Version),
+			field)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (dal *Dal) putVcTemplate(templateId string, templateName string, vcType, version string, vcTemplate string) error {
-	//将VcTemplate存入数据库
-	vcTemplateObj := standard.VcTemplate{
-		Id:       templateId,
-		Name:     templateName,
-		VcType:   vcType,
-		Template: vcTemplate,
-		Version:  version,
+func (dal *Dal) deleteVcTemplateIndex(vcTemplate *standard.VcTemplate) error {
+	err := dal.Db().DelState(keyTemplateByType, joinKey(vcTemplate.VcType, vcTemplate.Id, vcTemplate.Version))
+	if err != nil {
+		return err
+	}
+	if len(vcTemplate.Owner) != 0 {
+		err = dal.Db().DelState(keyTemplateByOwner, joinKey(vcTemplate.Owner, vcTemplate.Id, vcTemplate.Version))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchVcTemplateByIndex 通过索引分页查询VcTemplate，match在内存中再次检查，防止索引前缀匹配到其他记录
+func (dal *Dal) searchVcTemplateByIndex(key, prefix string, match func(vcTemplate *standard.VcTemplate) bool,
+	cursor string, count int) (*standard.Page[*standard.VcTemplate], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	p, err := newPager[*standard.VcTemplate](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	err = p.iterate(key, prefix, func(_ string, field []byte) error {
+		value, err1 := dal.Db().GetStateByte(keyVcTemplate, string(field))
+		if err1 != nil || len(value) == 0 {
+			return err1
+		}
+		var vcTemplate standard.VcTemplate
+		_ = json.Unmarshal(value, &vcTemplate)
+		if match(&vcTemplate) {
+			p.add(&vcTemplate)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.page(), nil
+}
+
+func (dal *Dal) putTemplateOwner(templateId string, owner string) error {
+	err := dal.Db().PutStateByte(keyTemplateOwner, encodeKey(templateId), []byte(owner))
+	if err != nil {
+		return err
 	}
-	value, _ := json.Marshal(vcTemplateObj)
-	err := dal.Db().PutStateByte(keyVcTemplate, templateId+"_"+version, value)
+	return nil
+}
+
+func (dal *Dal) putTemplateCompatibility(templateId string, compats []*standard.TemplateCompatibility) error {
+	value, _ := json.Marshal(compats)
+	err := dal.Db().PutStateByte(keyTemplateCompat, encodeKey(templateId), value)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (dal *Dal) getTemplateCompatibility(templateId string) ([]*standard.TemplateCompatibility, error) {
+	value, err := dal.Db().GetStateByte(keyTemplateCompat, encodeKey(templateId))
+	if err != nil {
+		return nil, err
+	}
+	compats := make([]*standard.TemplateCompatibility, 0)
+	if len(value) == 0 {
+		return compats, nil
+	}
+	err = json.Unmarshal(value, &compats)
+	if err != nil {
+		return nil, err
+	}
+	return compats, nil
+}
+
+// getTemplateOwner 获取模板所有者DID，旧版本合约创建的模板没有所有者
+func (dal *Dal) getTemplateOwner(templateId string) (string, error) {
+	owner, err := dal.Db().GetStateByte(keyTemplateOwner, encodeKey(templateId))
+	if err != nil {
+		return "", err
+	}
+	return string(owner), nil
+}
+
+// getVcTemplate 获取模板版本，先按规范化的语义化版本查找，再按原值查找旧合约保存的版本
 func (dal *Dal) getVcTemplate(templateId, version string) (*standard.VcTemplate, error) {
+	if normalized := normalizeTemplateVersion(version); normalized != version {
+		if vcTemplate, err := dal.getVcTemplateByKey(templateId, normalized); err == nil {
+			return vcTemplate, nil
+		}
+	}
+	return dal.getVcTemplateByKey(templateId, version)
+}
+
+func (dal *Dal) getVcTemplateByKey(templateId, version string) (*standard.VcTemplate, error) {
 	//从数据库中获取VcTemplate
-	value, err := dal.Db().GetStateByte(keyVcTemplate, templateId+"_"+version)
+	value, err := dal.getStateCompat(keyVcTemplate, joinKey(templateId, version),
+		legacyKeyVcTemplate, templateId+"_"+version)
 	if err != nil || len(value) == 0 {
 		return nil, errTemplateNotFound
 	}
@@ -439,57 +851,80 @@ func (dal *Dal) getVcTemplate(templateId, version string) (*standard.VcTemplate,
 	return &vcTemplateObj, nil
 }
 func (dal *Dal) getVcTemplateById(templateId string) ([]*standard.VcTemplate, error) {
-	//通过迭代器查询
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyVcTemplate, templateId+"_")
+	var vcTemplateSlice []*standard.VcTemplate
+	versions := make(map[string]bool)
+	err := dal.iteratePrefix(keyVcTemplate, encodeKey(templateId)+".", func(_ string, value []byte) error {
+		var vcTemplateObj standard.VcTemplate
+		_ = json.Unmarshal(value, &vcTemplateObj)
+		vcTemplateSlice = append(vcTemplateSlice, &vcTemplateObj)
+		versions[vcTemplateObj.Version] = true
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var vcTemplateSlice []*standard.VcTemplate
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
-		}
+	//迁移完成前还要读取旧表中的模板，旧表的字段为id_version
+	err = dal.iterateLegacy(legacyKeyVcTemplate, templateId+"_", func(value []byte) error {
 		var vcTemplateObj standard.VcTemplate
 		_ = json.Unmarshal(value, &vcTemplateObj)
-		vcTemplateSlice = append(vcTemplateSlice, &vcTemplateObj)
+		if vcTemplateObj.Id == templateId && !versions[vcTemplateObj.Version] {
+			vcTemplateSlice = append(vcTemplateSlice, &vcTemplateObj)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return vcTemplateSlice, nil
 }
 
-func (dal *Dal) searchVcTemplate(templateNameSearch string, start int, count int) ([]*standard.VcTemplate, error) {
-	//TODO:如果Name是中文，DockerGo不支持
-	//从数据库中查询VcTemplate迭代器,在内存中进行Name模糊搜索过滤
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyVcTemplate, "")
-	if err != nil {
+// searchVcTemplateByIdPrefix 根据模板ID前缀分页查询VcTemplate，encodeKey保持前缀关系
+func (dal *Dal) searchVcTemplateByIdPrefix(idPrefix string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	if err := dal.checkMigrated(); err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var vcTemplateSlice []*standard.VcTemplate
-	i := 0
-	if count == 0 {
-		count = defaultSearchCount
+	p, err := newPager[*standard.VcTemplate](dal, cursor, count)
+	if err != nil {
+		return nil, err
 	}
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
-		}
-		if i >= start+count {
-			break
+	err = p.iterate(keyVcTemplate, encodeKey(idPrefix), func(_ string, value []byte) error {
+		var vcTemplate standard.VcTemplate
+		_ = json.Unmarshal(value, &vcTemplate)
+		if strings.HasPrefix(vcTemplate.Id, idPrefix) {
+			p.add(&vcTemplate)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.page(), nil
+}
+
+func (dal *Dal) searchVcTemplate(templateNameSearch string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	p, err := newPager[*standard.VcTemplate](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	//从数据库中查询VcTemplate迭代器,在内存中对规范化后的Name进行模糊搜索过滤
+	nameSearch := normalizeTemplateName(templateNameSearch)
+	err = p.iterate(keyVcTemplate, "", func(_ string, value []byte) error {
 		var vcTemplateObj standard.VcTemplate
 		_ = json.Unmarshal(value, &vcTemplateObj)
-		if strings.Contains(vcTemplateObj.Name, templateNameSearch) {
-			vcTemplateSlice = append(vcTemplateSlice, &vcTemplateObj)
-			i++
-		}
-		if i < start {
-			continue
+		if strings.Contains(normalizeTemplateName(vcTemplateObj.Name), nameSearch) {
+			p.add(&vcTemplateObj)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return vcTemplateSlice, nil
+	return p.page(), nil
 }
 
 func (dal *Dal) putAdmin(admin string) error {
@@ -508,100 +943,455 @@ func (dal *Dal) getAdmin() (string, error) {
 	}
 	return admin, nil
 }
-func (dal *Dal) putVcIssueLog(issuer string, holder string, templateId string, vcID string) error {
+
+// vcIssueLogField VcIssueLog按持有人DID、发行者DID、模板ID或者内容哈希存储时的field
+// 发行时间补零为定长，同一索引下的日志按时间排序，可以按时间范围遍历，最后加上vcID，同一秒签发的不同VC不会互相覆盖
+func vcIssueLogField(index string, vcIssueLog *standard.VcIssueLog) string {
+	return vcIssueLogTimeField(index, vcIssueLog.IssueTime) + "." + encodeKey(vcIssueLog.VcID)
+}
+
+// vcIssueLogTimeField 索引下发行时间为issueTime的日志的起始field
+func vcIssueLogTimeField(index string, issueTime int64) string {
+	return fmt.Sprintf("%s.%019d", encodeKey(index), issueTime)
+}
+
+func (dal *Dal) putVcIssueLog(vcIssueLog *standard.VcIssueLog) error {
 	myTime, err := getTxTime()
 	if err != nil {
 		return err
 	}
-	//将VcIssueLog存入数据库
-	vcIssueLog := standard.VcIssueLog{
-		Issuer:     issuer,
-		Did:        holder,
-		TemplateId: templateId,
-		VcID:       vcID,
-		IssueTime:  myTime,
-	}
-	//将VcIssueLog存入数据库,用VC持有人DID作为key，但是为了防止重复而覆盖历史数据，需要加上时间戳和hash
+	vcIssueLog.IssueTime = myTime
+	//将VcIssueLog存入数据库,用VC持有人DID作为key
 	value, _ := json.Marshal(vcIssueLog)
-	hash := sha256.Sum256(value)
-	err = dal.Db().PutStateByte(keyVcIssueLog, processDid4Key(holder)+fmt.Sprintf("-%d-%x", myTime, hash[:2]), value)
+	err = dal.Db().PutStateByte(keyVcIssueLog, vcIssueLogField(vcIssueLog.Did, vcIssueLog), value)
+	if err != nil {
+		return err
+	}
+	//将VcIssueLog存入数据库,用VC ID和发行者作为key，方便后续搜索
+	err = dal.Db().PutStateByte(keyVcIndexIssueLog, joinKey(vcIssueLog.VcID, vcIssueLog.Issuer), value)
+	if err != nil {
+		return err
+	}
+	return dal.putVcIssueLogIndex(vcIssueLog, value)
+}
+
+// putVcIssueLogIndex 保存VcIssueLog按发行者、模板的索引，以及持有人的发行者列表
+func (dal *Dal) putVcIssueLogIndex(vcIssueLog *standard.VcIssueLog, value []byte) error {
+	err := dal.Db().PutStateByte(keyVcIssueLogByIssuer, vcIssueLogField(vcIssueLog.Issuer, vcIssueLog), value)
 	if err != nil {
 		return err
 	}
-	//将VcIssueLog存入数据库,用VC ID作为key，方便后续搜索
-	err = dal.Db().PutStateByte(keyVcIndexIssueLog, processVcId(vcID), value)
+	if len(vcIssueLog.TemplateId) != 0 {
+		err = dal.Db().PutStateByte(keyVcIssueLogByTemplate,
+			vcIssueLogField(vcIssueLog.TemplateId, vcIssueLog), value)
+		if err != nil {
+			return err
+		}
+	}
+	if len(vcIssueLog.VcHash) != 0 {
+		err = dal.Db().PutStateByte(keyVcIssueLogByHash, vcIssueLogField(vcIssueLog.VcHash, vcIssueLog), value)
+		if err != nil {
+			return err
+		}
+	}
+	err = dal.Db().PutStateByte(keyVcHolderIssuer, joinKey(vcIssueLog.Did, vcIssueLog.Issuer),
+		[]byte(vcIssueLog.Issuer))
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (dal *Dal) searchVcIssueLogByVcID(vcID string, start int, count int) ([]*standard.VcIssueLog, error) {
-	//从数据库中查询VcIssueLog迭代器
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyVcIndexIssueLog, processVcId(vcID))
+
+// getHolderVcIssueLogs 获取持有人的所有签发日志
+func (dal *Dal) getHolderVcIssueLogs(did string) ([]*standard.VcIssueLog, error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	var vcIssueLogs []*standard.VcIssueLog
+	err := dal.iteratePrefix(keyVcIssueLog, encodeKey(did)+".", func(_ string, value []byte) error {
+		var vcIssueLog standard.VcIssueLog
+		_ = json.Unmarshal(value, &vcIssueLog)
+		if vcIssueLog.Did == did {
+			vcIssueLogs = append(vcIssueLogs, &vcIssueLog)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var vcIssueLogSlice []*standard.VcIssueLog
-	i := 0
-	if count == 0 {
-		count = defaultSearchCount
+	return vcIssueLogs, nil
+}
+
+// getVcIssueLogsByVcID 获取vcID的签发日志，issuer不为空时只返回该发行者记录的日志
+func (dal *Dal) getVcIssueLogsByVcID(vcID string, issuer string) ([]*standard.VcIssueLog, error) {
+	var vcIssueLogs []*standard.VcIssueLog
+	issuers := make(map[string]bool)
+	prefix := encodeKey(vcID) + "."
+	if len(issuer) != 0 {
+		prefix = joinKey(vcID, issuer)
 	}
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
-		}
-		if i >= start+count {
-			break
+	err := dal.iteratePrefix(keyVcIndexIssueLog, prefix, func(_ string, value []byte) error {
+		var vcIssueLog standard.VcIssueLog
+		_ = json.Unmarshal(value, &vcIssueLog)
+		if vcIssueLog.VcID == vcID && (len(issuer) == 0 || vcIssueLog.Issuer == issuer) {
+			vcIssueLogs = append(vcIssueLogs, &vcIssueLog)
+			issuers[vcIssueLog.Issuer] = true
 		}
-		i++
-		if i < start {
-			continue
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	//迁移完成前还要读取旧表中的签发日志，新表中已有同一发行者的日志时以新表为准
+	err = dal.iterateLegacy(legacyKeyVcIndexIssueLog, processVcId(vcID), func(value []byte) error {
+		var vcIssueLog standard.VcIssueLog
+		_ = json.Unmarshal(value, &vcIssueLog)
+		if vcIssueLog.VcID == vcID && (len(issuer) == 0 || vcIssueLog.Issuer == issuer) && !issuers[vcIssueLog.Issuer] {
+			vcIssueLogs = append(vcIssueLogs, &vcIssueLog)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vcIssueLogs, nil
+}
+
+// getVcIssueLogsByHash 根据VC内容哈希获取签发日志
+func (dal *Dal) getVcIssueLogsByHash(vcHash string) ([]*standard.VcIssueLog, error) {
+	vcIssueLogs := make([]*standard.VcIssueLog, 0)
+	err := dal.iteratePrefix(keyVcIssueLogByHash, encodeKey(vcHash)+".", func(_ string, value []byte) error {
 		var vcIssueLog standard.VcIssueLog
-		_ = json.Unmarshal([]byte(value), &vcIssueLog)
-		vcIssueLogSlice = append(vcIssueLogSlice, &vcIssueLog)
+		_ = json.Unmarshal(value, &vcIssueLog)
+		if vcIssueLog.VcHash == vcHash {
+			vcIssueLogs = append(vcIssueLogs, &vcIssueLog)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return vcIssueLogSlice, nil
+	return vcIssueLogs, nil
 }
 
-func (dal *Dal) searchVcIssueLog(issuer string, did string, templateId string, start int, count int) (
-	[]*standard.VcIssueLog, error) {
-	//从数据库中查询VcIssueLog迭代器
-	iter, err := dal.Db().NewIteratorPrefixWithKeyField(keyVcIssueLog, processDid4Key(did))
+// isVcIssueLogged 判断vcID是否已经有签发日志，issuer不为空时只判断该发行者记录的日志
+func (dal *Dal) isVcIssueLogged(vcID string, issuer string) (bool, error) {
+	vcIssueLogs, err := dal.getVcIssueLogsByVcID(vcID, issuer)
 	if err != nil {
+		return false, err
+	}
+	return len(vcIssueLogs) != 0, nil
+}
+
+// searchVcIssueLog 分页查询VcIssueLog，优先使用持有人、发行者、模板索引，其余条件在内存中过滤
+// @param startTime 发行时间下限（包含），0表示不限制
+// @param endTime 发行时间上限（不包含），0表示不限制
+func (dal *Dal) searchVcIssueLog(issuer string, did string, templateId string, startTime int64, endTime int64,
+	cursor string, count int) (*standard.Page[*standard.VcIssueLog], error) {
+	if err := dal.checkMigrated(); err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-	var vcIssueLogSlice []*standard.VcIssueLog
-	i := 0
-	if count == 0 {
-		count = defaultSearchCount
+	p, err := newPager[*standard.VcIssueLog](dal, cursor, count)
+	if err != nil {
+		return nil, err
 	}
-	for iter.HasNext() {
-		_, _, value, err1 := iter.Next()
-		if err1 != nil {
-			return nil, err1
+	key, index := keyVcIssueLog, ""
+	switch {
+	case len(did) != 0:
+		index = did
+	case len(issuer) != 0:
+		key, index = keyVcIssueLogByIssuer, issuer
+	case len(templateId) != 0:
+		key, index = keyVcIssueLogByTemplate, templateId
+	}
+	//按持有人、发行者、模板过滤，索引已经保证其中一个条件
+	visit := func(_ string, value []byte) error {
+		var vcIssueLog standard.VcIssueLog
+		_ = json.Unmarshal(value, &vcIssueLog)
+		if len(did) != 0 && vcIssueLog.Did != did ||
+			len(issuer) != 0 && vcIssueLog.Issuer != issuer ||
+			len(templateId) != 0 && vcIssueLog.TemplateId != templateId {
+			return nil
 		}
-		if i >= start+count {
-			break
+		if len(index) == 0 && (vcIssueLog.IssueTime < startTime || endTime != 0 && vcIssueLog.IssueTime >= endTime) {
+			return nil
 		}
-		i++
-		if i < start {
-			continue
+		p.add(&vcIssueLog)
+		return nil
+	}
+	if len(index) == 0 {
+		//没有可用的索引时遍历所有日志，在内存中按时间过滤
+		err = p.iterate(key, "", visit)
+	} else {
+		//索引下的日志按发行时间排序，只遍历时间范围内的日志
+		if startTime < 0 {
+			startTime = 0
 		}
-		var vcIssueLog standard.VcIssueLog
-		_ = json.Unmarshal(value, &vcIssueLog)
-		if len(issuer) != 0 && vcIssueLog.Issuer != issuer {
-			i--
-			continue
+		limitField := encodeKey(index) + "." + fieldLimit
+		if endTime != 0 {
+			limitField = vcIssueLogTimeField(index, endTime)
+		}
+		err = p.iterateRange(key, vcIssueLogTimeField(index, startTime), limitField, visit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.page(), nil
+}
+
+// searchVcIssuers 查询持有人的发行者列表
+func (dal *Dal) searchVcIssuers(did string) ([]string, error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	var issuers []string
+	err := dal.iteratePrefix(keyVcHolderIssuer, encodeKey(did)+".", func(_ string, value []byte) error {
+		issuers = append(issuers, string(value))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issuers, nil
+}
+
+func (dal *Dal) putAdminTransfer(transfer *AdminTransfer) error {
+	//将AdminTransfer存入数据库
+	value, _ := json.Marshal(transfer)
+	err := dal.Db().PutStateFromKeyByte(keyAdminTransfer, value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) getAdminTransfer() (*AdminTransfer, error) {
+	//从数据库中获取AdminTransfer
+	value, err := dal.Db().GetStateFromKeyByte(keyAdminTransfer)
+	if err != nil || len(value) == 0 {
+		return nil, errDataNotFound
+	}
+	var transfer AdminTransfer
+	err = json.Unmarshal(value, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+func (dal *Dal) deleteAdminTransfer() error {
+	//从数据库中删除AdminTransfer
+	err := dal.Db().DelStateFromKey(keyAdminTransfer)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dal *Dal) putAdminCouncil(council *AdminCouncil) error {
+	//将AdminCouncil存入数据库
+	value, _ := json.Marshal(council)
+	err := dal.Db().PutStateFromKeyByte(keyAdminCouncil, value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) getAdminCouncil() (*AdminCouncil, error) {
+	//从数据库中获取AdminCouncil
+	value, err := dal.Db().GetStateFromKeyByte(keyAdminCouncil)
+	if err != nil || len(value) == 0 {
+		return nil, errDataNotFound
+	}
+	var council AdminCouncil
+	err = json.Unmarshal(value, &council)
+	if err != nil {
+		return nil, err
+	}
+	return &council, nil
+}
+
+// nextProposalId 生成递增的提案ID，补零以保证迭代顺序与创建顺序一致
+func (dal *Dal) nextProposalId() (string, error) {
+	seq, err := dal.Db().GetStateFromKey(keyProposalSeq)
+	if err != nil {
+		return "", err
+	}
+	next := int64(1)
+	if len(seq) != 0 {
+		current, err := strconv.ParseInt(seq, 10, 64)
+		if err != nil {
+			return "", err
 		}
-		if len(templateId) != 0 && vcIssueLog.TemplateId != templateId {
-			i--
-			continue
+		next = current + 1
+	}
+	err = dal.Db().PutStateFromKey(keyProposalSeq, strconv.FormatInt(next, 10))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016d", next), nil
+}
+func (dal *Dal) putProposal(proposal *Proposal) error {
+	//将Proposal存入数据库
+	value, _ := json.Marshal(proposal)
+	err := dal.Db().PutStateByte(keyProposal, proposal.Id, value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) getProposal(proposalId string) (*Proposal, error) {
+	//从数据库中获取Proposal
+	value, err := dal.Db().GetStateByte(keyProposal, proposalId)
+	if err != nil || len(value) == 0 {
+		return nil, errors.New("proposal not found")
+	}
+	var proposal Proposal
+	err = json.Unmarshal(value, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+func (dal *Dal) searchProposal(status string, cursor string, count int) (*standard.Page[*Proposal], error) {
+	p, err := newPager[*Proposal](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	//从数据库中查询Proposal迭代器,在内存中按状态过滤
+	err = p.iterate(keyProposal, "", func(_ string, value []byte) error {
+		var proposal Proposal
+		_ = json.Unmarshal(value, &proposal)
+		if len(status) == 0 || proposal.Status == status {
+			p.add(&proposal)
 		}
-		vcIssueLogSlice = append(vcIssueLogSlice, &vcIssueLog)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.page(), nil
+}
+
+func (dal *Dal) putRole(role string, did string) error {
+	//将Role存入数据库
+	err := dal.Db().PutStateByte(keyRole, joinKey(role, did), []byte(did))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) hasRole(role string, did string) bool {
+	//从数据库中获取Role
+	dbDid, err := dal.getStateCompat(keyRole, joinKey(role, did), legacyKeyRole, role+"_"+processDid4Key(did))
+	//旧数据的字段编码有冲突，需要检查存储的DID是否一致
+	if err != nil || string(dbDid) != did {
+		return false
+	}
+	return true
+}
+func (dal *Dal) deleteRole(role string, did string) error {
+	//从数据库中删除Role
+	err := dal.delStateCompat(keyRole, joinKey(role, did), legacyKeyRole, role+"_"+processDid4Key(did))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) searchRoleMembers(role string, cursor string, count int) (*standard.Page[string], error) {
+	if err := dal.checkMigrated(); err != nil {
+		return nil, err
+	}
+	p, err := newPager[string](dal, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+	//从数据库中查询Role迭代器
+	err = p.iterate(keyRole, encodeKey(role)+".", func(_ string, value []byte) error {
+		p.add(string(value))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.page(), nil
+}
+
+func (dal *Dal) putPause(operation string) error {
+	//将暂停的操作存入数据库
+	err := dal.Db().PutStateByte(keyPause, operation, []byte(operation))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) isPaused(operation string) bool {
+	//从数据库中获取暂停的操作
+	value, err := dal.Db().GetStateByte(keyPause, operation)
+	if err != nil || len(value) == 0 {
+		return false
+	}
+	return true
+}
+func (dal *Dal) deletePause(operation string) error {
+	//从数据库中删除暂停的操作
+	err := dal.Db().DelState(keyPause, operation)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dal *Dal) putConfig(config *ContractConfig) error {
+	//将Config存入数据库
+	value, _ := json.Marshal(config)
+	err := dal.Db().PutStateFromKeyByte(keyConfig, value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// getConfig 获取合约配置，未设置时返回默认配置
+func (dal *Dal) getConfig() *ContractConfig {
+	config := defaultConfig()
+	value, err := dal.Db().GetStateFromKeyByte(keyConfig)
+	if err != nil || len(value) == 0 {
+		return config
+	}
+	err = json.Unmarshal(value, config)
+	if err != nil {
+		return defaultConfig()
+	}
+	return config
+}
+
+// pageSize 计算分页查询的条数，未指定或者超过配置的最大值时使用最大值
+func (dal *Dal) pageSize(count int) int {
+	maxPageSize := dal.getConfig().MaxPageSize
+	if count <= 0 || count > maxPageSize {
+		return maxPageSize
+	}
+	return count
+}
+
+func (dal *Dal) putSchemaStatus(status *SchemaStatus) error {
+	//将存储版本存入数据库
+	value, _ := json.Marshal(status)
+	err := dal.Db().PutStateFromKeyByte(keySchema, value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (dal *Dal) getSchemaStatus() (*SchemaStatus, error) {
+	//从数据库中获取存储版本
+	value, err := dal.Db().GetStateFromKeyByte(keySchema)
+	if err != nil || len(value) == 0 {
+		return nil, errDataNotFound
+	}
+	var status SchemaStatus
+	err = json.Unmarshal(value, &status)
+	if err != nil {
+		return nil, err
 	}
-	return vcIssueLogSlice, nil
+	return &status, nil
 }
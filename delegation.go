@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bytes"
+	"did/standard"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+// maxDelegationDepth 授权链的最大长度
+const maxDelegationDepth = 8
+
+// 授权资源的模式，其他值按资源ID精确匹配
+const (
+	// delegateResourceAll 授权者的所有资源，与空字符串相同
+	delegateResourceAll = "*"
+	// delegateResourceTemplate 使用某个模板签发的所有vc
+	delegateResourceTemplate = "template:"
+	// delegateResourceType 某个类型的所有vc，匹配vc的type或者模板的vcType
+	delegateResourceType = "type:"
+	// delegateResourceIssuer 某个发行者签发的所有vc
+	delegateResourceIssuer = "issuer:"
+	// delegateActionAll 任意操作
+	delegateActionAll = "*"
+)
+
+// DID文档管理的授权操作，只能按名称显式授权，"*"不包含这些操作，授权资源必须是被管理的DID本身
+const (
+	// didActionUpdateDocument 任意修改DID文档，包含addService和rotateKey
+	didActionUpdateDocument = "updateDocument"
+	// didActionAddService 只新增service
+	didActionAddService = "addService"
+	// didActionRotateKey 只修改验证方法等密钥相关字段
+	didActionRotateKey = "rotateKey"
+	// didActionIssueLog 以发行者身份记录vc签发日志，与issue相同
+	didActionIssueLog = "issueLog"
+)
+
+// didManageActions 管理DID文档的操作
+var didManageActions = []string{didActionUpdateDocument, didActionAddService, didActionRotateKey}
+
+// didKeyFields DID文档中与密钥相关的字段
+var didKeyFields = []string{"verificationMethod", "authentication", "assertionMethod", "keyAgreement",
+	"capabilityInvocation", "capabilityDelegation"}
+
+var errDelegationNotFound = errors.New("delegation not found")
+
+// DelegateCapability 委托授权，被授权者在capabilityDelegation为true时可以将该授权再委托给他人
+// @param resource 选填，为空表示delegator的所有资源
+// @param expiration 选填，为0时不过期，再委托时与上一级授权一致
+// @param parentDelegatorDid 选填，再委托时授予发送者该授权的上一级DID，为空时发送者是授权链的起点
+// 再委托的resource、action不能超出上一级授权的范围，到期时间不能晚于上一级授权
+func (e *DidContract) DelegateCapability(delegateeDid string, resource string, action string, expiration int64,
+	capabilityDelegation bool, parentDelegatorDid string) error {
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return err
+	}
+	return e.saveDelegation(senderDid, delegateeDid, resource, action, expiration, capabilityDelegation,
+		parentDelegatorDid)
+}
+
+// DelegateWithProof 由中继者提交授权者签名的链下授权，授权者不需要发送交易
+// @param grantJson DelegationGrant的JSON，proof是授权者DID密钥对去掉proof后紧凑JSON的签名，
+// domain必须是合约配置的签名域，deadline之后不能再提交
+func (e *DidContract) DelegateWithProof(grantJson string) error {
+	grant := NewDelegationGrant(grantJson)
+	if grant == nil {
+		return errors.New("invalid delegation grant")
+	}
+	if len(grant.Delegator) == 0 || len(grant.Nonce) == 0 {
+		return errors.New("delegation grant delegator or nonce is empty")
+	}
+	domain, err := e.proofDomain()
+	if err != nil {
+		return err
+	}
+	if grant.Domain != domain {
+		return errors.New("delegation grant domain mismatch")
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	if grant.Deadline <= myTime {
+		return errors.New("delegation grant is expired")
+	}
+	if grant.Proof == nil {
+		return errors.New("invalid delegation grant, need proof")
+	}
+	if err = e.checkProofType(grant.Proof.Type); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(grant.Proof.VerificationMethod, grant.Delegator+"#") {
+		return errors.New("delegation grant is not signed by delegator")
+	}
+	if err = e.checkVerificationMethodBlackList(grant.Proof.VerificationMethod); err != nil {
+		return err
+	}
+	pass, err := grant.VerifySignature(e.getDidDocument)
+	if err != nil {
+		return err
+	}
+	if !pass {
+		return errors.New("invalid delegator signature")
+	}
+	used, err := e.dal.isDelegateNonceUsed(grant.Delegator, grant.Nonce)
+	if err != nil {
+		return err
+	}
+	if used {
+		return errors.New("delegation grant nonce already used")
+	}
+	err = e.saveDelegation(grant.Delegator, grant.Delegatee, grant.Resource, grant.Action, grant.Expiration,
+		grant.CapabilityDelegation, grant.ParentDelegator)
+	if err != nil {
+		return err
+	}
+	return e.dal.putDelegateNonce(grant.Delegator, grant.Nonce)
+}
+
+// saveDelegation 检查授权范围后保存delegatorDid给delegateeDid的授权，再委托时检查授权链
+func (e *DidContract) saveDelegation(delegatorDid string, delegateeDid string, resource string, action string,
+	expiration int64, capabilityDelegation bool, parentDelegatorDid string) error {
+	if len(delegateeDid) == 0 {
+		return errors.New("delegateeDid is empty")
+	}
+	resource, action, err := normalizeDelegateScope(resource, action)
+	if err != nil {
+		return err
+	}
+	if delegateeDid == delegatorDid {
+		return errors.New("can not delegate to self")
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	delegate := &standard.DelegateInfo{
+		DelegatorDid:         delegatorDid,
+		DelegateeDid:         delegateeDid,
+		Resource:             resource,
+		Action:               action,
+		StartTime:            myTime,
+		Expiration:           expiration,
+		CapabilityDelegation: capabilityDelegation,
+		ParentDelegator:      parentDelegatorDid,
+		RootDelegator:        delegatorDid,
+	}
+	if len(parentDelegatorDid) == 0 {
+		if delegate.Expiration == 0 {
+			delegate.Expiration = MaxDateTime
+		}
+	} else {
+		parent, err := e.findParentDelegation(delegate, "", myTime)
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			return errors.New("no re-delegable parent delegation covers this delegation")
+		}
+		delegate.RootDelegator = parent.RootDelegator
+		if len(delegate.RootDelegator) == 0 {
+			delegate.RootDelegator = parent.DelegatorDid
+		}
+		if delegateeDid == delegate.RootDelegator {
+			return errors.New("can not delegate back to the root delegator")
+		}
+		if delegate.Expiration == 0 {
+			delegate.Expiration = parent.Expiration
+		}
+		if _, err = e.validateDelegationChain(delegate, myTime); err != nil {
+			return err
+		}
+	}
+	if delegate.Expiration <= myTime {
+		return errors.New("delegation expiration must be later than now")
+	}
+	if err = e.dal.putDelegate(delegate); err != nil {
+		return err
+	}
+	e.EmitDelegateCapabilityEvent(delegate.DelegatorDid, delegate.DelegateeDid, delegate.Resource, delegate.Action,
+		delegate.StartTime, delegate.Expiration, delegate.CapabilityDelegation, delegate.ParentDelegator)
+	return nil
+}
+
+// EmitDelegateCapabilityEvent 发送可再委托的授权事件
+func (e *DidContract) EmitDelegateCapabilityEvent(delegatorDid string, delegateeDid string, resource string,
+	action string, start int64, expiration int64, capabilityDelegation bool, parentDelegatorDid string) {
+	sdk.Instance.EmitEvent(standard.Topic_DelegateCapability, []string{delegatorDid, delegateeDid, resource, action,
+		strconv.FormatInt(start, 10), strconv.FormatInt(expiration, 10), strconv.FormatBool(capabilityDelegation),
+		parentDelegatorDid})
+}
+
+// GetDelegationChain 获取delegatorDid到delegateeDid对resource的action操作的有效授权链，从起点开始排列
+// @param resource 选填，按资源ID匹配，为空时只匹配不限资源的授权
+func (e *DidContract) GetDelegationChain(delegatorDid string, delegateeDid string, resource string,
+	action string) ([]*standard.DelegateInfo, error) {
+	chain, err := e.findDelegationChain(delegatorDid, delegateeDid, action,
+		&delegateTarget{resources: []string{resource}})
+	if err != nil {
+		return nil, err
+	}
+	if chain == nil {
+		return nil, errDelegationNotFound
+	}
+	return chain, nil
+}
+
+// findDelegationChain 查找rootDid到delegateeDid对target执行action的有效授权链，没有时返回nil
+// 只读取rootDid与delegateeDid之间的直接授权和授权链索引，再在其中匹配资源和操作的模式
+func (e *DidContract) findDelegationChain(rootDid string, delegateeDid string, action string,
+	target *delegateTarget) ([]*standard.DelegateInfo, error) {
+	myTime, err := getTxTime()
+	if err != nil {
+		return nil, err
+	}
+	direct, err := e.dal.getDelegates(rootDid, delegateeDid)
+	if err != nil {
+		return nil, err
+	}
+	chained, err := e.dal.getDelegatesByRoot(rootDid, delegateeDid)
+	if err != nil {
+		return nil, err
+	}
+	for _, delegate := range append(direct, chained...) {
+		if delegate.DelegateeDid != delegateeDid || !isDelegationActive(delegate, myTime) {
+			continue
+		}
+		if delegationRoot(delegate) != rootDid {
+			continue
+		}
+		if !delegateMatch(delegate, action, target) {
+			continue
+		}
+		//上级授权已撤销或者过期的授权链无效，继续查找其他授权链
+		chain, err := e.validateDelegationChain(delegate, myTime)
+		if err != nil {
+			continue
+		}
+		//授权链的权限是每一级的交集
+		if delegationChainMatch(chain, action, target) {
+			return chain, nil
+		}
+	}
+	return nil, nil
+}
+
+// validateDelegationChain 从授权向上查找到起点，检查每一级都有效、可再委托，且范围和有效期不超过上一级
+func (e *DidContract) validateDelegationChain(leaf *standard.DelegateInfo, myTime int64) (
+	[]*standard.DelegateInfo, error) {
+	chain := []*standard.DelegateInfo{leaf}
+	for link := leaf; len(link.ParentDelegator) != 0; {
+		if len(chain) >= maxDelegationDepth {
+			return nil, errors.New("delegation chain is too long")
+		}
+		parent, err := e.findParentDelegation(link, link.RootDelegator, myTime)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, errors.New("parent delegation not found")
+		}
+		if delegationRoot(parent) != link.RootDelegator {
+			return nil, errors.New("delegation chain root mismatch")
+		}
+		if parent.Expiration < link.Expiration {
+			return nil, errors.New("delegation expires later than its parent")
+		}
+		chain = append([]*standard.DelegateInfo{parent}, chain...)
+		link = parent
+	}
+	return chain, nil
+}
+
+// findParentDelegation 查找授予link.DelegatorDid该授权的上一级有效、可再委托的授权，没有时返回nil
+// rootDid不为空时只查找起点是rootDid的上一级授权，同一上一级在多条授权链上的授权互不混淆
+func (e *DidContract) findParentDelegation(link *standard.DelegateInfo, rootDid string, myTime int64) (
+	*standard.DelegateInfo, error) {
+	delegates, err := e.dal.getDelegates(link.ParentDelegator, link.DelegatorDid)
+	if err != nil {
+		return nil, err
+	}
+	for _, delegate := range delegates {
+		if delegate.DelegatorDid != link.ParentDelegator || delegate.DelegateeDid != link.DelegatorDid {
+			continue
+		}
+		if !delegate.CapabilityDelegation || !isDelegationActive(delegate, myTime) {
+			continue
+		}
+		if len(rootDid) != 0 && delegationRoot(delegate) != rootDid {
+			continue
+		}
+		//上一级授权必须覆盖本级授权的范围
+		if !delegateActionCovers(delegate.Action, link.Action) || !delegateResourceCovers(delegate.Resource,
+			link.Resource) {
+			continue
+		}
+		return delegate, nil
+	}
+	return nil, nil
+}
+
+// delegationRoot 授权所在授权链的起点，直接授权的起点是授权者本人
+func delegationRoot(delegate *standard.DelegateInfo) string {
+	if len(delegate.ParentDelegator) == 0 {
+		return delegate.DelegatorDid
+	}
+	return delegate.RootDelegator
+}
+
+// isDelegationActive 授权在myTime时是否有效
+func isDelegationActive(delegate *standard.DelegateInfo, myTime int64) bool {
+	return delegate.StartTime <= myTime && delegate.Expiration > myTime
+}
+
+// delegateTarget 授权要操作的对象
+type delegateTarget struct {
+	// resources 按资源ID精确匹配，任意一个即可
+	resources  []string
+	templateId string
+	vcTypes    []string
+	issuer     string
+}
+
+// vcDelegateTarget 以vc作为授权对象
+func vcDelegateTarget(vc *VerifiableCredential) *delegateTarget {
+	target := &delegateTarget{
+		resources: []string{vc.ID},
+		vcTypes:   vc.Type,
+		issuer:    vc.Issuer,
+	}
+	if vc.Template != nil {
+		target.templateId = vc.Template.ID
+		target.vcTypes = append(target.vcTypes, vc.Template.VcType)
+	}
+	return target
+}
+
+// normalizeDelegateScope 检查并规范化授权的资源和操作，"*"资源存为空字符串，操作集合去重排序后用逗号连接
+func normalizeDelegateScope(resource string, action string) (string, string, error) {
+	if resource == delegateResourceAll {
+		resource = ""
+	}
+	for _, prefix := range []string{delegateResourceTemplate, delegateResourceType, delegateResourceIssuer} {
+		if strings.HasPrefix(resource, prefix) && len(resource) == len(prefix) {
+			return "", "", errors.New("delegate resource pattern is empty: " + resource)
+		}
+	}
+	var actions []string
+	for _, a := range strings.Split(action, ",") {
+		a = strings.TrimSpace(a)
+		if a == delegateActionAll {
+			return resource, delegateActionAll, nil
+		}
+		if len(a) != 0 && !isInList(a, actions) {
+			actions = append(actions, a)
+		}
+	}
+	if len(actions) == 0 {
+		return "", "", errors.New("delegate action is empty")
+	}
+	sort.Strings(actions)
+	return resource, strings.Join(actions, ","), nil
+}
+
+// delegateResourceMatch 授权资源是否匹配target
+// 空字符串匹配所有资源；"template:"、"type:"、"issuer:"分别匹配模板ID、vc类型、发行者；其他值匹配资源ID
+func delegateResourceMatch(resource string, target *delegateTarget) bool {
+	switch {
+	case len(resource) == 0:
+		return true
+	case strings.HasPrefix(resource, delegateResourceTemplate):
+		return len(target.templateId) != 0 && resource[len(delegateResourceTemplate):] == target.templateId
+	case strings.HasPrefix(resource, delegateResourceType):
+		return isInList(resource[len(delegateResourceType):], target.vcTypes)
+	case strings.HasPrefix(resource, delegateResourceIssuer):
+		return len(target.issuer) != 0 && resource[len(delegateResourceIssuer):] == target.issuer
+	default:
+		return isInList(resource, target.resources)
+	}
+}
+
+// delegationChainMatch 授权链的每一级是否都匹配action和target
+func delegationChainMatch(chain []*standard.DelegateInfo, action string, target *delegateTarget) bool {
+	for _, link := range chain {
+		if !delegateMatch(link, action, target) {
+			return false
+		}
+	}
+	return true
+}
+
+// delegateMatch 授权的操作和资源是否匹配action和target
+// 管理DID文档的操作必须按名称授权，资源必须是target中的DID，不能通过"*"或者空资源获得
+func delegateMatch(delegate *standard.DelegateInfo, action string, target *delegateTarget) bool {
+	if isInList(action, didManageActions) {
+		return isInList(action, strings.Split(delegate.Action, ",")) && isInList(delegate.Resource, target.resources)
+	}
+	return delegateActionMatch(delegate.Action, action) && delegateResourceMatch(delegate.Resource, target)
+}
+
+// delegateActionMatch 授权的操作集合是否包含action
+func delegateActionMatch(actions string, action string) bool {
+	return actions == delegateActionAll || isInList(action, strings.Split(actions, ","))
+}
+
+// delegateResourceCovers 上一级授权的资源是否覆盖本级授权的资源
+// 模式只能收窄为相同模式或者具体资源ID，使用时每一级都要匹配，具体资源ID不在上一级模式内时授权链无效
+func delegateResourceCovers(parent string, child string) bool {
+	if len(parent) == 0 || parent == child {
+		return true
+	}
+	return isDelegateResourcePattern(parent) && !isDelegateResourcePattern(child)
+}
+
+// delegateActionCovers 上一级授权的操作集合是否包含本级授权的所有操作
+func delegateActionCovers(parent string, child string) bool {
+	if parent == delegateActionAll {
+		return true
+	}
+	if child == delegateActionAll {
+		return false
+	}
+	parentActions := strings.Split(parent, ",")
+	for _, action := range strings.Split(child, ",") {
+		if !isInList(action, parentActions) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDelegateResourcePattern 资源是否是模式而不是具体资源ID
+func isDelegateResourcePattern(resource string) bool {
+	return len(resource) == 0 || strings.HasPrefix(resource, delegateResourceTemplate) ||
+		strings.HasPrefix(resource, delegateResourceType) || strings.HasPrefix(resource, delegateResourceIssuer)
+}
+
+// isDelegatedAny 判断delegator是否授权delegatee对target执行actions中的任意一个操作
+func (e *DidContract) isDelegatedAny(delegator string, delegatee string, actions []string,
+	target *delegateTarget) (bool, error) {
+	for _, action := range actions {
+		delegated, err := e.isDelegated(delegator, delegatee, action, target)
+		if err != nil || delegated {
+			return delegated, err
+		}
+	}
+	return false, nil
+}
+
+// checkDidDocumentDelegation 检查senderDid是否得到DID所有者修改DID文档的授权，需要的操作由文档的修改内容决定
+func (e *DidContract) checkDidDocumentDelegation(didDoc *DIDDocument, senderDid string) error {
+	oldDidDocument, err := e.dal.getDidDocument(didDoc.ID)
+	if err != nil {
+		return err
+	}
+	action, err := didDocumentChangeAction(oldDidDocument, didDoc.rawData)
+	if err != nil {
+		return err
+	}
+	actions := []string{action}
+	if action != didActionUpdateDocument {
+		actions = append(actions, didActionUpdateDocument)
+	}
+	delegated, err := e.isDelegatedAny(didDoc.ID, senderDid, actions, &delegateTarget{resources: []string{didDoc.ID}})
+	if err != nil {
+		return err
+	}
+	if !delegated {
+		return errors.New("only admin, registrar, did owner or its " + action + " delegatee can update did document")
+	}
+	return nil
+}
+
+// didDocumentChangeAction 根据新旧DID文档的差异得出需要的授权操作，proof和updated不计入差异
+// 只新增service需要addService，只修改密钥相关字段需要rotateKey，其他修改需要updateDocument
+func didDocumentChangeAction(oldDidDocument []byte, newDidDocument []byte) (string, error) {
+	var oldFields, newFields map[string]json.RawMessage
+	if err := json.Unmarshal(oldDidDocument, &oldFields); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(newDidDocument, &newFields); err != nil {
+		return "", err
+	}
+	var changed []string
+	for _, fields := range []map[string]json.RawMessage{oldFields, newFields} {
+		for field := range fields {
+			if field == proof || field == "updated" || isInList(field, changed) {
+				continue
+			}
+			if !jsonEqual(oldFields[field], newFields[field]) {
+				changed = append(changed, field)
+			}
+		}
+	}
+	onlyKeys := true
+	for _, field := range changed {
+		if !isInList(field, didKeyFields) {
+			onlyKeys = false
+			break
+		}
+	}
+	switch {
+	case len(changed) == 0 || len(changed) == 1 && changed[0] == "service" &&
+		isServiceAdded(oldFields["service"], newFields["service"]):
+		return didActionAddService, nil
+	case onlyKeys:
+		return didActionRotateKey, nil
+	default:
+		return didActionUpdateDocument, nil
+	}
+}
+
+// isServiceAdded 新的service列表是否保留了所有旧的service
+func isServiceAdded(oldServices json.RawMessage, newServices json.RawMessage) bool {
+	var oldList, newList []json.RawMessage
+	if len(oldServices) != 0 && json.Unmarshal(oldServices, &oldList) != nil {
+		return false
+	}
+	if json.Unmarshal(newServices, &newList) != nil {
+		return false
+	}
+	for _, oldService := range oldList {
+		found := false
+		for _, newService := range newList {
+			if jsonEqual(oldService, newService) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual 比较两个JSON值去掉空白后是否相同
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	compactA, errA := compactJson(a)
+	compactB, errB := compactJson(b)
+	return errA == nil && errB == nil && bytes.Equal(compactA, compactB)
+}
@@ -13,6 +13,7 @@ package main
 
 import (
 	"did/standard"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,6 +31,7 @@ import (
 const (
 	didMethod             = "cnbn"
 	defaultDelegateAction = "sign"
+	issueDelegateAction   = "issue"
 	defaultSearchCount    = 1000
 )
 
@@ -47,6 +49,8 @@ var _ standard.CMBC = (*DidContract)(nil)
 // DidContract 存证合约实现
 type DidContract struct {
 	dal *Dal
+	// executingProposal 是否正在执行管理员委员会审批通过的提案
+	executingProposal bool
 }
 
 // NewDidContract 创建存证合约实例
@@ -80,19 +84,43 @@ func (e *DidContract) InitAdmin(didJson string) error {
 	if err != nil {
 		return err
 	}
+	//新安装的合约直接使用最新的存储版本，先保存版本，否则写入管理员后会被当作没有保存版本的旧合约数据
+	if _, err = e.dal.getSchemaStatus(); err != nil {
+		if _, err = e.dal.getAdmin(); err != nil {
+			err = e.dal.putSchemaStatus(&SchemaStatus{Version: currentSchemaVersion(),
+				TargetVersion: currentSchemaVersion(), Done: true})
+			if err != nil {
+				return err
+			}
+		}
+	}
 	err = e.dal.putAdmin(adminDid)
 	if err != nil {
 		return err
 	}
+	//重新设置管理员时，取消未完成的管理员转移
+	if _, err = e.dal.getAdminTransfer(); err == nil {
+		err = e.dal.deleteAdminTransfer()
+		if err != nil {
+			return err
+		}
+	}
+	//重新设置管理员时，已有的管理员委员会也重置为只有新管理员一人
+	if _, err = e.dal.getAdminCouncil(); err == nil {
+		return e.dal.putAdminCouncil(&AdminCouncil{Admins: []string{adminDid}, Threshold: 1})
+	}
 	return nil
 }
 
-// SetAdmin 修改合约管理员
+// SetAdmin 直接修改合约管理员，保留旧版本的行为，填错DID会导致合约无法管理，建议使用ProposeAdmin、AcceptAdmin两步转移
 func (e *DidContract) SetAdmin(did string) error {
 	//检查sender是否是admin
 	if !e.isAdmin() {
 		return errors.New("only admin can set admin")
 	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
 	//检查did是否有效
 	valid, err := e.IsValidDid(did)
 	if err != nil {
@@ -101,11 +129,133 @@ func (e *DidContract) SetAdmin(did string) error {
 	if !valid {
 		return errInvalidDid
 	}
-	//保存admin
-	err = e.dal.putAdmin(did)
+	oldAdmin, err := e.dal.getAdmin()
+	if err != nil {
+		return err
+	}
+	//直接修改后，未完成的管理员转移不再有效
+	if _, err = e.dal.getAdminTransfer(); err == nil {
+		if err = e.dal.deleteAdminTransfer(); err != nil {
+			return err
+		}
+	}
+	return e.replaceAdmin(oldAdmin, did)
+}
+
+// replaceAdmin 保存新管理员，如果设置了管理员委员会，则用新管理员替换委员会中的旧管理员
+func (e *DidContract) replaceAdmin(oldAdmin string, newAdmin string) error {
+	err := e.dal.putAdmin(newAdmin)
+	if err != nil {
+		return err
+	}
+	if council, err := e.dal.getAdminCouncil(); err == nil && !council.IsMember(newAdmin) {
+		for i, admin := range council.Admins {
+			if admin == oldAdmin {
+				council.Admins[i] = newAdmin
+				return e.dal.putAdminCouncil(council)
+			}
+		}
+	}
+	return nil
+}
+
+// ProposeAdmin 发起管理员转移
+// @param did 新管理员DID
+// @param delay 延迟生效时间，单位秒，新管理员需要在延迟结束后才能接受
+func (e *DidContract) ProposeAdmin(did string, delay int64) error {
+	//检查sender是否是admin
+	if !e.isAdmin() {
+		return errors.New("only admin can propose admin")
+	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
+	if delay < 0 {
+		return errors.New("invalid delay")
+	}
+	//检查did是否有效
+	valid, err := e.IsValidDid(did)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errInvalidDid
+	}
+	oldAdmin, err := e.dal.getAdmin()
+	if err != nil {
+		return err
+	}
+	if oldAdmin == did {
+		return errors.New("did is already admin")
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	transfer := &AdminTransfer{
+		From:          oldAdmin,
+		To:            did,
+		ProposeTime:   myTime,
+		EffectiveTime: myTime + delay,
+	}
+	err = e.dal.putAdminTransfer(transfer)
+	if err != nil {
+		return err
+	}
+	e.EmitProposeAdminEvent(transfer)
+	return nil
+}
+
+// AcceptAdmin 新管理员接受管理员转移，必须由新管理员DID对应的地址发送
+func (e *DidContract) AcceptAdmin() error {
+	transfer, err := e.dal.getAdminTransfer()
+	if err != nil {
+		return errors.New("no pending admin transfer")
+	}
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return err
+	}
+	if senderDid != transfer.To {
+		return errors.New("only pending admin can accept admin")
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	if myTime < transfer.EffectiveTime {
+		return errors.New("admin transfer is still time locked")
+	}
+	err = e.replaceAdmin(transfer.From, transfer.To)
+	if err != nil {
+		return err
+	}
+	err = e.dal.deleteAdminTransfer()
+	if err != nil {
+		return err
+	}
+	e.EmitAcceptAdminEvent(transfer)
+	return nil
+}
+
+// CancelAdminTransfer 取消管理员转移，管理员或者待接受的新管理员可以取消
+func (e *DidContract) CancelAdminTransfer() error {
+	transfer, err := e.dal.getAdminTransfer()
+	if err != nil {
+		return errors.New("no pending admin transfer")
+	}
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return err
+	}
+	if senderDid != transfer.To && !e.isAdmin() {
+		return errors.New("only admin or pending admin can cancel admin transfer")
+	}
+	err = e.dal.deleteAdminTransfer()
 	if err != nil {
 		return err
 	}
+	e.EmitCancelAdminTransferEvent(transfer)
 	return nil
 }
 
@@ -114,6 +264,39 @@ func (e *DidContract) GetAdmin() (string, error) {
 	return e.dal.getAdmin()
 }
 
+// GetAdminInfo 获取合约管理员DID、未完成的管理员转移以及管理员委员会
+func (e *DidContract) GetAdminInfo() (*AdminInfo, error) {
+	adminDid, err := e.dal.getAdmin()
+	if err != nil {
+		return nil, err
+	}
+	info := &AdminInfo{Admin: adminDid}
+	if transfer, err := e.dal.getAdminTransfer(); err == nil {
+		info.PendingTransfer = transfer
+	}
+	info.Council, err = e.GetAdminCouncil()
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// EmitProposeAdminEvent 发送发起管理员转移事件
+func (e *DidContract) EmitProposeAdminEvent(transfer *AdminTransfer) {
+	sdk.Instance.EmitEvent(standard.Topic_ProposeAdmin, []string{transfer.From, transfer.To,
+		strconv.FormatInt(transfer.EffectiveTime, 10)})
+}
+
+// EmitAcceptAdminEvent 发送接受管理员转移事件
+func (e *DidContract) EmitAcceptAdminEvent(transfer *AdminTransfer) {
+	sdk.Instance.EmitEvent(standard.Topic_AcceptAdmin, []string{transfer.From, transfer.To})
+}
+
+// EmitCancelAdminTransferEvent 发送取消管理员转移事件
+func (e *DidContract) EmitCancelAdminTransferEvent(transfer *AdminTransfer) {
+	sdk.Instance.EmitEvent(standard.Topic_CancelAdminTransfer, []string{transfer.From, transfer.To})
+}
+
 // DidMethod 获取DID Method
 func (e *DidContract) DidMethod() string {
 	return didMethod
@@ -137,6 +320,13 @@ func (e *DidContract) IsValidDid(did string) (bool, error) {
 	if err != nil || len(didDocumentJson) == 0 {
 		return false, errDidNotFound
 	}
+	deactivated, err := e.dal.isDidDeactivated(did)
+	if err != nil {
+		return false, err
+	}
+	if deactivated {
+		return false, errDidDeactivated
+	}
 	return true, nil
 }
 
@@ -145,6 +335,14 @@ func (e *DidContract) getDidDocument(did string) (*DIDDocument, error) {
 	if err != nil || len(didDocumentJson) == 0 {
 		return nil, errors.New("did document not found, did=" + did)
 	}
+	//已注销DID的密钥不能再用于验证签名
+	deactivated, err := e.dal.isDidDeactivated(did)
+	if err != nil {
+		return nil, err
+	}
+	if deactivated {
+		return nil, errDidDeactivated
+	}
 	didDoc := NewDIDDocument(string(didDocumentJson))
 	if didDoc == nil {
 		return nil, errors.New("invalid did document")
@@ -185,6 +383,12 @@ func (e *DidContract) verifyDidDocument(didDoc *DIDDocument) error {
 	if didDoc.Proof == nil {
 		return errors.New("invalid did document, need proof")
 	}
+	for _, proof := range didDoc.GetProofs() {
+		err = e.checkProofType(proof.Type)
+		if err != nil {
+			return err
+		}
+	}
 	pass, err := didDoc.VerifySignature(func(_did string) (*DIDDocument, error) {
 		//如果是DID用户自己签名，那么DID Document还没有上链，直接返回didDoc
 		if _did == did {
@@ -205,11 +409,15 @@ func (e *DidContract) verifyDidDocument(didDoc *DIDDocument) error {
 
 // AddDidDocument 添加DID Document
 func (e *DidContract) AddDidDocument(didDocument string) error {
+	err := e.checkDocumentSize(didDocument)
+	if err != nil {
+		return err
+	}
 	didDoc := NewDIDDocument(didDocument)
 	if didDoc == nil {
 		return errors.New("invalid did document")
 	}
-	err := e.verifyDidDocument(didDoc)
+	err = e.verifyDidDocument(didDoc)
 	if err != nil {
 		return err
 	}
@@ -289,6 +497,10 @@ func (e *DidContract) GetDidDocument(did string) (string, error) {
 
 // GetDidByPubkey 根据公钥获取DID
 func (e *DidContract) GetDidByPubkey(pk string) (string, error) {
+	//检查公钥是否在黑名单中
+	if e.dal.isInBlackList(pk) {
+		return "", errors.New("public key is in black list")
+	}
 	//get did by pubkey
 	did, err := e.dal.getDidByPubKey(pk)
 	if err != nil {
@@ -307,6 +519,10 @@ func (e *DidContract) GetDidByPubkey(pk string) (string, error) {
 
 // GetDidDocumentByPubkey 根据公钥获取DID Document
 func (e *DidContract) GetDidDocumentByPubkey(pk string) (string, error) {
+	//检查公钥是否在黑名单中
+	if e.dal.isInBlackList(pk) {
+		return "", errors.New("public key is in black list")
+	}
 	//get did by pubkey
 	did, err := e.dal.getDidByPubKey(pk)
 	if err != nil {
@@ -318,6 +534,10 @@ func (e *DidContract) GetDidDocumentByPubkey(pk string) (string, error) {
 
 // GetDidByAddress 根据地址获取DID
 func (e *DidContract) GetDidByAddress(address string) (string, error) {
+	//检查地址是否在黑名单中
+	if e.dal.isInBlackList(address) {
+		return "", errors.New("address is in black list")
+	}
 	//get did by address
 	did, err := e.dal.getDidByAddress(address)
 	if err != nil {
@@ -336,6 +556,10 @@ func (e *DidContract) GetDidByAddress(address string) (string, error) {
 
 // GetDidDocumentByAddress 根据地址获取DID Document
 func (e *DidContract) GetDidDocumentByAddress(address string) (string, error) {
+	//检查地址是否在黑名单中
+	if e.dal.isInBlackList(address) {
+		return "", errors.New("address is in black list")
+	}
 	//get did by address
 	did, err := e.dal.getDidByAddress(address)
 	if err != nil {
@@ -347,25 +571,51 @@ func (e *DidContract) GetDidDocumentByAddress(address string) (string, error) {
 
 // VerifyVc 验证VC的有效性
 func (e *DidContract) VerifyVc(vcJson string) (bool, error) {
+	return e.verifyVc(vcJson, schemaCache{})
+}
+
+// verifyVc 验证VC凭证，schemas缓存本次交易中已编译的模板Schema
+func (e *DidContract) verifyVc(vcJson string, schemas schemaCache) (bool, error) {
 
 	vc := NewVerifiableCredential(vcJson)
 	if vc == nil {
 		return false, errors.New("invalid vc")
 	}
-	if EnableVcIssueLog {
-		//检查vcId是否在VcIssueLog表中
-		vcIssueLogs, err := e.dal.searchVcIssueLogByVcID(vc.ID, 0, 1)
+	config := e.dal.getConfig()
+	if config.EnableVcIssueLog {
+		//检查vcId是否由vc的发行者记录在VcIssueLog表中
+		issued, err := e.dal.isVcIssueLogged(vc.ID, vc.Issuer)
 		if err != nil {
 			return false, err
 		}
-		if len(vcIssueLogs) == 0 {
+		if !issued {
 			return false, errors.New("vc is not issued")
 		}
+		//检查vc内容是否与签发时记录的哈希一致
+		if err = e.checkVcContentHash(vc); err != nil {
+			return false, err
+		}
 	}
 	//检查vc拥有者是否在黑名单中
 	if e.dal.isInBlackList(vc.GetCredentialSubjectID()) {
 		return false, errors.New("vc owner is in black list")
 	}
+	//检查vc发行者是否在黑名单中
+	if e.dal.isInBlackList(vc.Issuer) {
+		return false, errors.New("vc issuer is in black list")
+	}
+	//检查vc签名所用的验证方法、公钥、地址是否在黑名单中
+	if vc.Proof == nil {
+		return false, errors.New("invalid vc, need proof")
+	}
+	err := e.checkProofType(vc.Proof.Type)
+	if err != nil {
+		return false, err
+	}
+	err = e.checkVerificationMethodBlackList(vc.Proof.VerificationMethod)
+	if err != nil {
+		return false, err
+	}
 	// Check if the issuance date is before the expiration date
 	issuanceDate, err := time.Parse(time.RFC3339, vc.IssuanceDate)
 	if err != nil {
@@ -385,7 +635,7 @@ func (e *DidContract) VerifyVc(vcJson string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if myTime < issuanceDate.Unix() || myTime > expirationDate.Unix() {
+	if myTime+config.ClockSkew < issuanceDate.Unix() || myTime-config.ClockSkew > expirationDate.Unix() {
 		return false, errors.New("vc is expired")
 	}
 	// Check if the VC type is correct
@@ -393,7 +643,7 @@ func (e *DidContract) VerifyVc(vcJson string) (bool, error) {
 		return false, errors.New("invalid VC type")
 	}
 	//Check Issuer Validity
-	if EnableTrustIssuer {
+	if config.EnableTrustIssuer {
 		err = e.checkIssuer(vc.Issuer)
 		if err != nil {
 			return false, err
@@ -409,13 +659,17 @@ func (e *DidContract) VerifyVc(vcJson string) (bool, error) {
 	}
 	//检查vc template
 	if vc.Template != nil {
-		vcTemplate, err := e.dal.getVcTemplate(vc.Template.ID, vc.Template.Version)
+		vcTemplate, err := e.resolveVcTemplateForVc(vc.Template.ID, vc.Template.Version)
 		if err != nil {
 			return false, err
 		}
 		if vcTemplate == nil {
 			return false, errors.New("invalid VC template")
 		}
+		//草稿模板还没有发布，已停用的模板仍然可以验证之前签发的VC
+		if templateStatus(vcTemplate) == standard.TemplateStatusDraft {
+			return false, errors.New("vc template is not published")
+		}
 		//检查vc template name
 		if vcTemplate.Name != vc.Template.Name {
 			return false, errors.New("invalid VC template name")
@@ -424,13 +678,29 @@ func (e *DidContract) VerifyVc(vcJson string) (bool, error) {
 			return false, errors.New("invalid VC type")
 		}
 		//检查vc template
-		result, err := vc.VerifyVcTemplateContent(vcTemplate.Template)
+		subjectSchema, err := schemas.load(vcTemplate, schemaKindSubject, vcTemplate.Template)
+		if err != nil {
+			return false, err
+		}
+		result, err := vc.verifySubjectSchema(subjectSchema)
 		if err != nil {
 			return false, err
 		}
 		if !result {
 			return false, errors.New("credentialSubject of VC not match template")
 		}
+		if len(vcTemplate.CredentialSchema) != 0 {
+			credentialSchema, err := schemas.load(vcTemplate, schemaKindCredential, vcTemplate.CredentialSchema)
+			if err != nil {
+				return false, err
+			}
+			if _, err = vc.verifyCredentialSchema(credentialSchema); err != nil {
+				return false, err
+			}
+		}
+		if err = checkVcTemplateMetadata(vc, vcTemplate.Metadata); err != nil {
+			return false, err
+		}
 	}
 	//检查是否被撤销
 	if e.isInRevokeVcList(vc.ID) {
@@ -439,6 +709,37 @@ func (e *DidContract) VerifyVc(vcJson string) (bool, error) {
 	return true, nil
 }
 
+// checkVerificationMethodBlackList 检查验证方法ID及其对应的DID、公钥、地址是否在黑名单中
+func (e *DidContract) checkVerificationMethodBlackList(vm string) error {
+	index := strings.Index(vm, "#")
+	if index < 0 {
+		return errors.New("invalid verification method")
+	}
+	if e.dal.isInBlackList(vm) {
+		return errors.New("verification method is in black list")
+	}
+	signerDid := vm[0:index]
+	if e.dal.isInBlackList(signerDid) {
+		return errors.New("signer did is in black list")
+	}
+	signerDidDoc, err := e.getDidDocument(signerDid)
+	if err != nil {
+		return err
+	}
+	for _, pk := range signerDidDoc.VerificationMethod {
+		if pk.ID != vm {
+			continue
+		}
+		if e.dal.isInBlackList(pk.PublicKeyPem) {
+			return errors.New("public key is in black list")
+		}
+		if len(pk.Address) != 0 && e.dal.isInBlackList(pk.Address) {
+			return errors.New("address is in black list")
+		}
+	}
+	return nil
+}
+
 func (e *DidContract) isInRevokeVcList(id string) bool {
 	dbId, err := e.dal.getRevokeVc(id)
 	if err != nil || len(dbId) == 0 {
@@ -466,10 +767,6 @@ func getTxTime() (int64, error) {
 
 // VerifyVp 验证VP的有效性
 func (e *DidContract) VerifyVp(vpJson string) (bool, error) {
-	myTime, err := getTxTime()
-	if err != nil {
-		return false, err
-	}
 	vp := NewVerifiablePresentation(vpJson)
 	if vp == nil {
 		return false, errors.New("invalid vp")
@@ -478,42 +775,48 @@ func (e *DidContract) VerifyVp(vpJson string) (bool, error) {
 	if vp.Type != "VerifiablePresentation" {
 		return false, errors.New("invalid VP type")
 	}
+	if vp.Proof == nil || !strings.Contains(vp.Proof.VerificationMethod, "#") {
+		return false, errors.New("invalid vp proof")
+	}
+	err := e.checkProofType(vp.Proof.Type)
+	if err != nil {
+		return false, err
+	}
 	//验证亮证人是否在黑名单中
 	userDid := vp.Proof.VerificationMethod[0:strings.Index(vp.Proof.VerificationMethod, "#")]
 	if e.dal.isInBlackList(userDid) {
 		return false, errors.New("vp owner is in black list")
 	}
+	//验证亮证人签名所用的验证方法、公钥、地址是否在黑名单中
+	err = e.checkVerificationMethodBlackList(vp.Proof.VerificationMethod)
+	if err != nil {
+		return false, err
+	}
 	// Validate all VCs in the VP
-	for _, vc := range vp.VerifiableCredential {
-		vcString, _ := json.Marshal(vc)
-		//验证vc的有效性
-		_, err = e.VerifyVc(string(vcString))
+	schemas := schemaCache{}
+	rawVcs, err := vp.RawCredentials()
+	if err != nil {
+		return false, err
+	}
+	if len(rawVcs) != len(vp.VerifiableCredential) {
+		return false, errors.New("invalid verifiableCredential in vp")
+	}
+	for i, vc := range vp.VerifiableCredential {
+		//按原始json验证vc的有效性，多个vc共用同一模板时只编译一次Schema
+		_, err = e.verifyVc(rawVcs[i], schemas)
 		if err != nil {
 			return false, fmt.Errorf("invalid VC: %w", err)
 		}
-		//如果userDid和vc中的id不一致，则验证是否存在delegate，如果没有对应的delegate，则验证失败
+		//如果userDid和vc中的id不一致，则验证是否存在从vc持有人到userDid的有效授权链，没有则验证失败
 		if userDid != vc.GetCredentialSubjectID() {
-			//验证是否存在delegate
-			//delegates, err1 := e.dal.searchDelegate(userDid, vc.GetCredentialSubjectID(), vc.ID,
-			delegates, err1 := e.dal.searchDelegate(vc.GetCredentialSubjectID(), userDid, vc.ID,
-				defaultDelegateAction, 0, 0)
+			chain, err1 := e.findDelegationChain(vc.GetCredentialSubjectID(), userDid, defaultDelegateAction,
+				vcDelegateTarget(&vc))
 			if err1 != nil {
 				return false, err1
 			}
-			if len(delegates) == 0 {
+			if chain == nil {
 				return false, errors.New("no delegate")
 			}
-			//验证delegate是否过期
-			hasDelegate := false
-			for _, delegate := range delegates {
-				if delegate.StartTime <= myTime && delegate.Expiration > myTime {
-					hasDelegate = true
-					break
-				}
-			}
-			if !hasDelegate {
-				return false, errors.New("delegate is expired")
-			}
 		}
 	}
 
@@ -541,6 +844,9 @@ func (e *DidContract) EmitSetDidDocumentEvent(did string, didDocument string) {
 
 // SetTrustRootList 设置信任根列表
 func (e *DidContract) SetTrustRootList(dids []string) error {
+	if err := e.checkPermission(RoleTrustManager, true); err != nil {
+		return err
+	}
 	// check did valid
 	for _, did := range dids {
 		valid, err := e.IsValidDid(did)
@@ -585,8 +891,8 @@ func (e *DidContract) EmitSetTrustRootListEvent(dids []string) {
 
 // RevokeVc 撤销VC
 func (e *DidContract) RevokeVc(vcID string) error {
-	if !e.isAdmin() {
-		return errors.New("only admin can revoke vc")
+	if err := e.checkPermission(RoleRevoker, true); err != nil {
+		return err
 	}
 	err := e.dal.putRevokeVc(vcID)
 	if err != nil {
@@ -597,8 +903,8 @@ func (e *DidContract) RevokeVc(vcID string) error {
 }
 
 // GetRevokedVcList 获取撤销VC列表
-func (e *DidContract) GetRevokedVcList(vcIDSearch string, start int, count int) ([]string, error) {
-	return e.dal.searchRevokeVc(vcIDSearch, start, count)
+func (e *DidContract) GetRevokedVcList(vcIDSearch string, cursor string, count int) (*standard.Page[string], error) {
+	return e.dal.searchRevokeVc(vcIDSearch, cursor, count)
 }
 
 // EmitRevokeVcEvent 发送撤销VC事件
@@ -608,6 +914,10 @@ func (e *DidContract) EmitRevokeVcEvent(vcID string) {
 
 // UpdateDidDocument 更新DID Document
 func (e *DidContract) UpdateDidDocument(didDocument string) error {
+	err := e.checkDocumentSize(didDocument)
+	if err != nil {
+		return err
+	}
 	didDoc := NewDIDDocument(didDocument)
 	if didDoc == nil {
 		return errors.New("invalid did document")
@@ -617,11 +927,24 @@ func (e *DidContract) UpdateDidDocument(didDocument string) error {
 	if err != nil {
 		return err
 	}
-	if senderDid != didDoc.ID {
-		if !e.isAdmin() {
-			return errors.New("only admin or did owner can update did document")
+	if senderDid != didDoc.ID && !e.isAdmin() && !e.senderHasRole(RoleRegistrar) {
+		//DID所有者可以授权其他DID管理自己的DID文档
+		if err = e.checkDidDocumentDelegation(didDoc, senderDid); err != nil {
+			return err
 		}
 	}
+	return e.updateDidDocument(didDoc, didDocument)
+}
+
+// updateDidDocument 检查并保存新的DID Document，更新公钥和地址索引，调用者负责检查权限
+func (e *DidContract) updateDidDocument(didDoc *DIDDocument, didDocument string) error {
+	deactivated, err := e.dal.isDidDeactivated(didDoc.ID)
+	if err != nil {
+		return err
+	}
+	if deactivated {
+		return errDidDeactivated
+	}
 	//检查新DID Document有效性
 	err = e.verifyDidDocument(didDoc)
 	if err != nil {
@@ -698,11 +1021,23 @@ func isInList(pk string, keys []string) bool {
 	return false
 }
 
-// AddBlackList 添加黑名单
-func (e *DidContract) AddBlackList(dids []string) error {
-	if !e.isAdmin() {
-		return errors.New("only admin can add black list")
+// AddBlackList 添加黑名单，并记录原因、证据和自动失效时间
+func (e *DidContract) AddBlackList(dids []string, reason string, evidence string, expiration int64) error {
+	if err := e.checkPermission(RoleBlackListManager, true); err != nil {
+		return err
+	}
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return err
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	if expiration != 0 && expiration <= myTime {
+		return errors.New("invalid expiration")
 	}
+	entries := make([]*standard.BlackListEntry, 0, len(dids))
 	for _, did := range dids {
 		//// check did valid
 		//valid, err := e.IsValidDid(did)
@@ -712,12 +1047,21 @@ func (e *DidContract) AddBlackList(dids []string) error {
 		//if !valid {
 		//	return errors.New(did + " not found")
 		//}
-		err := e.dal.putBlackList(did)
+		entry := &standard.BlackListEntry{
+			Did:        did,
+			Reason:     reason,
+			Evidence:   evidence,
+			Creator:    senderDid,
+			CreateTime: myTime,
+			Expiration: expiration,
+		}
+		err = e.dal.putBlackList(entry)
 		if err != nil {
 			return err
 		}
+		entries = append(entries, entry)
 	}
-	e.EmitAddBlackListEvent(dids)
+	e.EmitAddBlackListEvent(entries)
 	return nil
 }
 
@@ -765,8 +1109,8 @@ func (e *DidContract) AddBlackList(dids []string) error {
 
 // DeleteBlackList 删除黑名单
 func (e *DidContract) DeleteBlackList(dids []string) error {
-	if !e.isAdmin() {
-		return errors.New("only admin can delete black list")
+	if err := e.checkPermission(RoleBlackListManager, false); err != nil {
+		return err
 	}
 	for _, did := range dids {
 		err := e.dal.deleteBlackList(did)
@@ -779,13 +1123,19 @@ func (e *DidContract) DeleteBlackList(dids []string) error {
 }
 
 // GetBlackList 获取黑名单
-func (e *DidContract) GetBlackList(didSearch string, start int, count int) ([]string, error) {
-	return e.dal.searchBlackList(didSearch, start, count)
+func (e *DidContract) GetBlackList(didSearch string, cursor string, count int) (
+	*standard.Page[*standard.BlackListEntry], error) {
+	return e.dal.searchBlackList(didSearch, cursor, count)
+}
+
+// GetBlackListEntry 获取黑名单记录
+func (e *DidContract) GetBlackListEntry(did string) (*standard.BlackListEntry, error) {
+	return e.dal.getBlackList(did)
 }
 
 // EmitAddBlackListEvent 发送添加黑名单事件
-func (e *DidContract) EmitAddBlackListEvent(dids []string) {
-	value, _ := json.Marshal(dids)
+func (e *DidContract) EmitAddBlackListEvent(entries []*standard.BlackListEntry) {
+	value, _ := json.Marshal(entries)
 	sdk.Instance.EmitEvent(standard.Topic_AddBlackList, []string{string(value)})
 }
 
@@ -797,8 +1147,8 @@ func (e *DidContract) EmitDeleteBlackListEvent(dids []string) {
 
 // AddTrustIssuer 添加信任发行者
 func (e *DidContract) AddTrustIssuer(dids []string) error {
-	if !e.isAdmin() {
-		return errors.New("only admin can add trust issuer")
+	if err := e.checkPermission(RoleTrustManager, true); err != nil {
+		return err
 	}
 	for _, did := range dids {
 		// check did valid
@@ -820,8 +1170,8 @@ func (e *DidContract) AddTrustIssuer(dids []string) error {
 
 // DeleteTrustIssuer 删除信任发行者
 func (e *DidContract) DeleteTrustIssuer(dids []string) error {
-	if !e.isAdmin() {
-		return errors.New("only admin can delete trust issuer")
+	if err := e.checkPermission(RoleTrustManager, false); err != nil {
+		return err
 	}
 	for _, did := range dids {
 		// check did valid
@@ -842,8 +1192,8 @@ func (e *DidContract) DeleteTrustIssuer(dids []string) error {
 }
 
 // GetTrustIssuer 获取信任发行者
-func (e *DidContract) GetTrustIssuer(didSearch string, start int, count int) ([]string, error) {
-	return e.dal.searchTrustIssuer(didSearch, start, count)
+func (e *DidContract) GetTrustIssuer(didSearch string, cursor string, count int) (*standard.Page[string], error) {
+	return e.dal.searchTrustIssuer(didSearch, cursor, count)
 }
 
 // EmitAddTrustIssuerEvent 发送添加信任发行者事件
@@ -865,15 +1215,43 @@ func (e *DidContract) getSenderDid() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return e.dal.getDidByAddress(sender)
+	senderDid, err := e.dal.getDidByAddress(sender)
+	if err != nil {
+		return "", err
+	}
+	//注销的DID保留地址索引，但不能再作为交易发送者
+	deactivated, err := e.dal.isDidDeactivated(senderDid)
+	if err != nil {
+		return "", err
+	}
+	if deactivated {
+		return "", errDidDeactivated
+	}
+	return senderDid, nil
+}
+
+// isDelegated 判断delegator是否授权delegatee在当前时间对target执行action，匹配规则见delegateResourceMatch
+func (e *DidContract) isDelegated(delegator string, delegatee string, action string, target *delegateTarget) (
+	bool, error) {
+	chain, err := e.findDelegationChain(delegator, delegatee, action, target)
+	if err != nil {
+		return false, err
+	}
+	return chain != nil, nil
 }
 
 // Delegate 委托设置
+// @param resource 资源，可以是VcID，也可以是"*"、"template:模板ID"、"type:vc类型"、"issuer:发行者DID"
+// @param action 操作，多个操作用逗号分隔，"*"表示任意操作；管理DID文档的操作见didActionUpdateDocument等
 func (e *DidContract) Delegate(delegateeDid string, resource string, action string, expiration int64) error {
 	exp := MaxDateTime
 	if expiration != 0 {
 		exp = expiration
 	}
+	resource, action, err := normalizeDelegateScope(resource, action)
+	if err != nil {
+		return err
+	}
 	senderDid, err := e.getSenderDid()
 	if err != nil {
 		return err
@@ -906,8 +1284,12 @@ func (e *DidContract) EmitDelegateEvent(delegatorDid string, delegateeDid string
 		strconv.FormatInt(start, 10), strconv.FormatInt(expiration, 10)})
 }
 
-// RevokeDelegate 撤销委托
+// RevokeDelegate 撤销委托，发送者给delegatee对resource、action的直接授权和各授权链上的再委托都会撤销
 func (e *DidContract) RevokeDelegate(delegateeDid string, resource string, action string) error {
+	resource, action, err := normalizeDelegateScope(resource, action)
+	if err != nil {
+		return err
+	}
 	senderDid, err := e.getSenderDid()
 	if err != nil {
 		return err
@@ -926,10 +1308,19 @@ func (e *DidContract) EmitRevokeDelegateEvent(delegatorDid string, delegateeDid
 	sdk.Instance.EmitEvent(standard.Topic_RevokeDelegate, []string{delegatorDid, delegateeDid, resource, action})
 }
 
+// GetDelegationsReceived 获取delegateeDid收到的所有授权
+func (e *DidContract) GetDelegationsReceived(delegateeDid string, cursor string, count int) (
+	*standard.Page[*standard.DelegateInfo], error) {
+	if len(delegateeDid) == 0 {
+		return nil, errors.New("delegateeDid is empty")
+	}
+	return e.dal.getDelegationsReceived(delegateeDid, cursor, count)
+}
+
 // GetDelegateList 获取委托列表
 func (e *DidContract) GetDelegateList(delegatorDid, delegateeDid string, resource string, action string,
-	start int, count int) ([]*standard.DelegateInfo, error) {
-	return e.dal.searchDelegate(delegatorDid, delegateeDid, resource, action, start, count)
+	cursor string, count int) (*standard.Page[*standard.DelegateInfo], error) {
+	return e.dal.searchDelegate(delegatorDid, delegateeDid, resource, action, cursor, count)
 }
 
 func checkTemplateValid(template string) error {
@@ -943,43 +1334,33 @@ func checkTemplateValid(template string) error {
 	return err
 }
 
-// SetVcTemplate 设置VC模板
+// SetVcTemplate 发布VC模板，已发布的版本不能修改，只能发布新版本
 func (e *DidContract) SetVcTemplate(id string, name string, vcType, version string, template string) error {
-	if !e.isAdmin() {
-		return errors.New("only admin can set vc template")
-	}
-	err := checkTemplateValid(template)
-	if err != nil {
-		return errors.New("invalid vc template: " + err.Error())
-	}
-	err = e.dal.putVcTemplate(id, name, vcType, version, template)
-	if err != nil {
-		return err
-	}
-	e.EmitSetVcTemplateEvent(id, name, vcType, version, template)
-	return nil
+	return e.saveVcTemplate(id, name, vcType, version, template, "", "", standard.TemplateStatusActive)
 }
 func (e *DidContract) isAdmin() bool {
 	senderDid, err := e.getSenderDid()
 	if err != nil {
 		return false
 	}
-	adminDid, err := e.dal.getAdmin()
+	//管理员委员会的任一成员都是管理员
+	council, err := e.GetAdminCouncil()
 	if err != nil {
 		return false
 	}
-	return senderDid == adminDid
+	return council.IsMember(senderDid)
 }
 
 // GetVcTemplate 获取VC模板
+// @param version 具体版本，或者latest、^1.2、~1.2等查询
 func (e *DidContract) GetVcTemplate(id, version string) (*standard.VcTemplate, error) {
-	return e.dal.getVcTemplate(id, version)
+	return e.resolveVcTemplate(id, version)
 }
 
 // GetVcTemplateList 获取VC模板列表
-func (e *DidContract) GetVcTemplateList(templateNameSearch string, start int, count int) (
-	[]*standard.VcTemplate, error) {
-	return e.dal.searchVcTemplate(templateNameSearch, start, count)
+func (e *DidContract) GetVcTemplateList(templateNameSearch string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	return e.dal.searchVcTemplate(templateNameSearch, cursor, count)
 }
 
 // EmitSetVcTemplateEvent 发送设置VC模板事件
@@ -988,34 +1369,246 @@ func (e *DidContract) EmitSetVcTemplateEvent(templateId, templateName, vcType, v
 		[]string{templateId, templateName, vcType, version, vcTemplate})
 }
 
-// VcIssueLog 记录VC签发日志
-func (e *DidContract) VcIssueLog(issuer string, did string, templateId string, vcID string) error {
-	//检查Issuer，did，templateId的有效性
+// VcIssueLog 记录VC签发日志，只有发行者本人或者得到发行者"issue"或"issueLog"授权的DID可以记录，
+// 其他发行者已经记录过的vcID不能再记录，vcHash为VC去掉proof后JCS（RFC 8785）规范化JSON的哈希
+// @param templateVersion vc引用的模板版本或者范围，该版本必须是可以签发的状态
+func (e *DidContract) VcIssueLog(issuer string, did string, templateId string, templateVersion string, vcID string,
+	vcHash string, hashAlgorithm string, expiration int64) error {
+	vcIssueLog, err := e.newVcIssueLog(issuer, did, templateId, templateVersion, vcID, vcHash, hashAlgorithm,
+		expiration)
+	if err != nil {
+		return err
+	}
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return err
+	}
+	if senderDid != issuer {
+		target := &delegateTarget{resources: []string{templateId, vcID}, templateId: templateId, issuer: issuer}
+		delegated, err1 := e.isDelegatedAny(issuer, senderDid, []string{issueDelegateAction, didActionIssueLog},
+			target)
+		if err1 != nil {
+			return err1
+		}
+		if !delegated {
+			return errors.New("only issuer or its issue delegatee can log vc issuance")
+		}
+	}
+	vcIssueLogs, err := e.dal.getVcIssueLogsByVcID(vcID, "")
+	if err != nil {
+		return err
+	}
+	for _, l := range vcIssueLogs {
+		if l.Issuer != issuer {
+			return errors.New("vc issue log already exists for another issuer")
+		}
+	}
+	return e.saveVcIssueLog(vcIssueLog)
+}
+
+// vcIssueLogPayload VcIssueLogWithProof中发行者签名的内容
+type vcIssueLogPayload struct {
+	// Domain 合约配置的签名域，签名不能在其他链或者合约上使用
+	Domain          string `json:"domain"`
+	Issuer          string `json:"issuer"`
+	Did             string `json:"did"`
+	TemplateId      string `json:"templateID"`
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	VcID            string `json:"vcID"`
+	VcHash          string `json:"vcHash,omitempty"`
+	HashAlgorithm   string `json:"hashAlgorithm,omitempty"`
+	Expiration      int64  `json:"expiration,omitempty"`
+	// Deadline 签名的提交截止时间，必填
+	Deadline int64 `json:"deadline"`
+}
+
+// VcIssueLogWithProof 由中继者提交发行者签名的VC签发日志，同一个vcID只能通过签名记录一次，防止重放
+// @param deadline 签名的提交截止时间，超过后签名失效
+// @param proofJson 发行者对vcIssueLogPayload紧凑JSON的签名，domain为合约配置的签名域，没有的可选字段省略
+func (e *DidContract) VcIssueLogWithProof(issuer string, did string, templateId string, templateVersion string,
+	vcID string, vcHash string, hashAlgorithm string, expiration int64, deadline int64, proofJson string) error {
+	vcIssueLog, err := e.newVcIssueLog(issuer, did, templateId, templateVersion, vcID, vcHash, hashAlgorithm,
+		expiration)
+	if err != nil {
+		return err
+	}
+	domain, err := e.proofDomain()
+	if err != nil {
+		return err
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	if deadline <= myTime {
+		return errors.New("vc issue log proof is expired")
+	}
+	var p Proof
+	if err = json.Unmarshal([]byte(proofJson), &p); err != nil {
+		return errors.New("invalid proof")
+	}
+	if err = e.checkProofType(p.Type); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(p.VerificationMethod, issuer+"#") {
+		return errors.New("proof is not signed by issuer")
+	}
+	if err = e.checkVerificationMethodBlackList(p.VerificationMethod); err != nil {
+		return err
+	}
+	payload, _ := json.Marshal(vcIssueLogPayload{Domain: domain, Issuer: issuer, Did: did, TemplateId: templateId,
+		TemplateVersion: templateVersion, VcID: vcID, VcHash: vcIssueLog.VcHash,
+		HashAlgorithm: vcIssueLog.HashAlgorithm, Expiration: expiration, Deadline: deadline})
+	pass, err := verifySignature(e.getDidDocument, &p, payload)
+	if err != nil {
+		return err
+	}
+	if !pass {
+		return errors.New("invalid issuer signature")
+	}
+	issued, err := e.dal.isVcIssueLogged(vcID, "")
+	if err != nil {
+		return err
+	}
+	if issued {
+		return errors.New("vc issue log already exists")
+	}
+	return e.saveVcIssueLog(vcIssueLog)
+}
+
+// newVcIssueLog 检查Issuer，did，模板版本的有效性，规范化vcHash后生成VC签发日志
+func (e *DidContract) newVcIssueLog(issuer string, did string, templateId string, templateVersion string,
+	vcID string, vcHash string, hashAlgorithm string, expiration int64) (*standard.VcIssueLog, error) {
+	if expiration < 0 {
+		return nil, errors.New("invalid expiration")
+	}
 	valid, err := e.IsValidDid(issuer)
 	if err != nil || !valid {
-		return errInvalidDid
+		return nil, errInvalidDid
 	}
 	valid, err = e.IsValidDid(did)
 	if err != nil || !valid {
-		return errInvalidDid
+		return nil, errInvalidDid
+	}
+	if len(templateId) != 0 {
+		templateVersion, err = e.checkTemplateIssuable(templateId, templateVersion)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(templateVersion) != 0 {
+		return nil, errors.New("templateID is empty")
+	}
+	vcHash, hashAlgorithm, err = normalizeVcHash(vcHash, hashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &standard.VcIssueLog{
+		Issuer:          issuer,
+		Did:             did,
+		TemplateId:      templateId,
+		TemplateVersion: templateVersion,
+		VcID:            vcID,
+		VcHash:          vcHash,
+		HashAlgorithm:   hashAlgorithm,
+		Expiration:      expiration,
+	}, nil
+}
+
+// normalizeVcHash 检查vcHash是否是对应算法的十六进制哈希，并统一为小写
+func normalizeVcHash(vcHash string, hashAlgorithm string) (string, string, error) {
+	if len(vcHash) == 0 {
+		if len(hashAlgorithm) != 0 {
+			return "", "", errors.New("vcHash is empty")
+		}
+		return "", "", nil
+	}
+	if len(hashAlgorithm) == 0 {
+		hashAlgorithm = defaultHashAlgorithm
+	}
+	hashAlgorithm = strings.ToLower(hashAlgorithm)
+	hashFunc, ok := vcHashAlgorithms[hashAlgorithm]
+	if !ok {
+		return "", "", errors.New("unsupported hash algorithm: " + hashAlgorithm)
 	}
-	templates, err := e.dal.getVcTemplateById(templateId)
-	if err != nil || len(templates) == 0 {
+	vcHash = strings.ToLower(vcHash)
+	hash, err := hex.DecodeString(vcHash)
+	if err != nil || len(hash) != len(hashFunc(nil)) {
+		return "", "", errors.New("invalid vcHash")
+	}
+	return vcHash, hashAlgorithm, nil
+}
+
+// saveVcIssueLog 保存VC签发日志并发送事件
+func (e *DidContract) saveVcIssueLog(vcIssueLog *standard.VcIssueLog) error {
+	err := e.dal.putVcIssueLog(vcIssueLog)
+	if err != nil {
 		return err
 	}
-	//保存VC签发日志
-	err = e.dal.putVcIssueLog(issuer, did, templateId, vcID)
+	e.EmitVcIssueLogEvent(vcIssueLog.Issuer, vcIssueLog.Did, vcIssueLog.TemplateId, vcIssueLog.VcID)
+	return nil
+}
+
+// GetVcIssueLogByHash 根据VC内容哈希获取VC签发日志
+func (e *DidContract) GetVcIssueLogByHash(vcHash string) ([]*standard.VcIssueLog, error) {
+	vcHash = strings.ToLower(vcHash)
+	if len(vcHash) == 0 {
+		return nil, errors.New("vcHash is empty")
+	}
+	return e.dal.getVcIssueLogsByHash(vcHash)
+}
+
+// checkVcContentHash 如果VC发行者的签发日志记录了VC内容哈希，VC内容必须与其中之一一致
+func (e *DidContract) checkVcContentHash(vc *VerifiableCredential) error {
+	vcIssueLogs, err := e.dal.getVcIssueLogsByVcID(vc.ID, vc.Issuer)
 	if err != nil {
 		return err
 	}
-	e.EmitVcIssueLogEvent(issuer, did, templateId, vcID)
+	hasHash := false
+	for _, vcIssueLog := range vcIssueLogs {
+		if len(vcIssueLog.VcHash) == 0 {
+			continue
+		}
+		hasHash = true
+		vcHash, err1 := vc.ContentHash(vcIssueLog.HashAlgorithm)
+		if err1 != nil {
+			return err1
+		}
+		if vcHash == vcIssueLog.VcHash {
+			return nil
+		}
+	}
+	if hasHash {
+		return errors.New("vc content does not match issue log")
+	}
 	return nil
 }
 
 // GetVcIssueLogs 获取VC签发日志
-func (e *DidContract) GetVcIssueLogs(issuer string, did string, templateId string, start int, count int) (
-	[]*standard.VcIssueLog, error) {
-	return e.dal.searchVcIssueLog(issuer, did, templateId, start, count)
+func (e *DidContract) GetVcIssueLogs(issuer string, did string, templateId string, cursor string, count int) (
+	*standard.Page[*standard.VcIssueLog], error) {
+	return e.dal.searchVcIssueLog(issuer, did, templateId, 0, 0, cursor, count)
+}
+
+// GetVcIssueLogsByIssuer 根据发行者DID获取VC签发日志
+// @param startTime 发行时间下限（包含），0表示不限制
+// @param endTime 发行时间上限（不包含），0表示不限制
+func (e *DidContract) GetVcIssueLogsByIssuer(issuer string, startTime int64, endTime int64, cursor string,
+	count int) (*standard.Page[*standard.VcIssueLog], error) {
+	if len(issuer) == 0 {
+		return nil, errors.New("issuer is empty")
+	}
+	return e.dal.searchVcIssueLog(issuer, "", "", startTime, endTime, cursor, count)
+}
+
+// GetVcIssueLogsByTemplate 根据模板ID获取VC签发日志
+// @param startTime 发行时间下限（包含），0表示不限制
+// @param endTime 发行时间上限（不包含），0表示不限制
+func (e *DidContract) GetVcIssueLogsByTemplate(templateId string, startTime int64, endTime int64, cursor string,
+	count int) (*standard.Page[*standard.VcIssueLog], error) {
+	if len(templateId) == 0 {
+		return nil, errors.New("templateID is empty")
+	}
+	return e.dal.searchVcIssueLog("", "", templateId, startTime, endTime, cursor, count)
 }
 
 // EmitVcIssueLogEvent 发送VC签发日志事件
@@ -1025,15 +1618,14 @@ func (e *DidContract) EmitVcIssueLogEvent(issuer string, did string, templateId
 
 // GetVcIssuers 获取VC签发者列表
 func (e *DidContract) GetVcIssuers(did string) ([]string, error) {
-	issueLogs, err := e.GetVcIssueLogs("", did, "", 0, 0)
+	//持有人的发行者列表在记录签发日志时维护，不需要加载所有签发日志
+	issuers, err := e.dal.searchVcIssuers(did)
 	if err != nil {
 		return nil, err
 	}
 	issuerDidMap := make(map[string]bool)
-	for _, issueLog := range issueLogs {
-		if _, ok := issuerDidMap[issueLog.Issuer]; !ok {
-			issuerDidMap[issueLog.Issuer] = true
-		}
+	for _, issuer := range issuers {
+		issuerDidMap[issuer] = true
 	}
 	//按Key排序,并返回
 	issuerDid := make([]string, 0)
@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -13,7 +16,18 @@ import (
 	"github.com/xeipuuv/gojsonschema"
 )
 
-const proof = "proof"
+const (
+	proof                = "proof"
+	defaultHashAlgorithm = "sha256"
+)
+
+// vcHashAlgorithms 支持的VC内容哈希算法
+var vcHashAlgorithms = map[string]func(data []byte) []byte{
+	defaultHashAlgorithm: func(data []byte) []byte {
+		hash := sha256.Sum256(data)
+		return hash[:]
+	},
+}
 
 // GetDidDocument 根据DID URL获取DID文档
 type GetDidDocument func(did string) (*DIDDocument, error)
@@ -207,7 +221,9 @@ type VerifiableCredential struct {
 		Version string `json:"version"`
 		VcType  string `json:"vcType"`
 	} `json:"template,omitempty"`
-	Proof *Proof `json:"proof,omitempty"`
+	Evidence   json.RawMessage `json:"evidence,omitempty"`
+	TermsOfUse json.RawMessage `json:"termsOfUse,omitempty"`
+	Proof      *Proof          `json:"proof,omitempty"`
 }
 
 // NewVerifiableCredential 根据VC凭证json字符串创建VC凭证
@@ -226,6 +242,47 @@ func (vc *VerifiableCredential) GetCredentialSubjectID() string {
 	return vc.CredentialSubject["id"].(string)
 }
 
+// ContentHash 计算VC凭证去掉proof后JCS（RFC 8785）规范化JSON的哈希，返回十六进制字符串
+// 规范化后key按字典序排列，VC的key顺序和空白不影响哈希
+func (vc *VerifiableCredential) ContentHash(hashAlgorithm string) (string, error) {
+	hashFunc, ok := vcHashAlgorithms[hashAlgorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported hash algorithm: %s", hashAlgorithm)
+	}
+	withoutProof := jsonparser.Delete(vc.rawData, proof)
+	withoutProof, err := canonicalJson(withoutProof)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hashFunc(withoutProof)), nil
+}
+
+// entryTypes 获取evidence、termsOfUse等条目的type，条目可以是单个对象或者数组，type可以是字符串或者数组
+func entryTypes(raw json.RawMessage) []string {
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		var entry map[string]interface{}
+		if err = json.Unmarshal(raw, &entry); err != nil {
+			return nil
+		}
+		entries = append(entries, entry)
+	}
+	var types []string
+	for _, entry := range entries {
+		switch t := entry["type"].(type) {
+		case string:
+			types = append(types, t)
+		case []interface{}:
+			for _, item := range t {
+				if s, ok := item.(string); ok {
+					types = append(types, s)
+				}
+			}
+		}
+	}
+	return types
+}
+
 // VerifySignature 验证VC凭证的签名
 func (vc *VerifiableCredential) VerifySignature(getDidDocument GetDidDocument) (bool, error) {
 	withoutProof := jsonparser.Delete(vc.rawData, proof)
@@ -242,11 +299,27 @@ func (vc *VerifiableCredential) VerifyVcTemplateContent(vcTemplate string) (bool
 	if len(vcTemplate) == 0 {
 		return false, fmt.Errorf("vcTemplate is empty")
 	}
-	schemaLoader := gojsonschema.NewStringLoader(vcTemplate)
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(vcTemplate))
+	if err != nil {
+		return false, err
+	}
+	return vc.verifySubjectSchema(schema)
+}
+
+// verifySubjectSchema 使用已编译的Schema验证credentialSubject
+func (vc *VerifiableCredential) verifySubjectSchema(schema *gojsonschema.Schema) (bool, error) {
 	data, _ := json.Marshal(vc.CredentialSubject)
-	dataLoader := gojsonschema.NewStringLoader(string(data))
+	return validateSchema(schema, data, "credentialSubject")
+}
 
-	result, err := gojsonschema.Validate(schemaLoader, dataLoader)
+// verifyCredentialSchema 使用已编译的Schema验证整个VC凭证（不含proof）
+func (vc *VerifiableCredential) verifyCredentialSchema(schema *gojsonschema.Schema) (bool, error) {
+	return validateSchema(schema, jsonparser.Delete(vc.rawData, proof), "credential")
+}
+
+// validateSchema 使用Schema验证JSON数据
+func validateSchema(schema *gojsonschema.Schema, data []byte, name string) (bool, error) {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(data))
 	if err != nil {
 		return false, err
 	}
@@ -254,12 +327,51 @@ func (vc *VerifiableCredential) VerifyVcTemplateContent(vcTemplate string) (bool
 	if result.Valid() {
 		return true, nil
 	}
-	errMsg := "Invalid credentialSubject, errors:"
+	errMsg := "Invalid " + name + ", errors:"
 	for _, desc := range result.Errors() {
 		errMsg += fmt.Sprintf("- %s\n", desc)
 	}
 	return false, fmt.Errorf(errMsg)
+}
 
+// DelegationGrant 授权者用DID密钥签名的链下授权，任何人都可以代为提交上链
+type DelegationGrant struct {
+	rawData json.RawMessage
+	// Domain 合约配置的签名域，授权不能在其他链或者合约上提交
+	Domain               string `json:"domain"`
+	Delegator            string `json:"delegator"`
+	Delegatee            string `json:"delegatee"`
+	Resource             string `json:"resource,omitempty"`
+	Action               string `json:"action"`
+	Expiration           int64  `json:"expiration,omitempty"`
+	CapabilityDelegation bool   `json:"capabilityDelegation,omitempty"`
+	ParentDelegator      string `json:"parentDelegator,omitempty"`
+	// Nonce 授权者生成的随机数，同一授权者的nonce只能使用一次
+	Nonce string `json:"nonce"`
+	// Deadline 授权的提交截止时间，超过后不能再提交，与授权的有效期Expiration无关
+	Deadline int64  `json:"deadline"`
+	Proof    *Proof `json:"proof,omitempty"`
+}
+
+// NewDelegationGrant 根据授权json字符串创建链下授权
+func NewDelegationGrant(grantJson string) *DelegationGrant {
+	var grant DelegationGrant
+	err := json.Unmarshal([]byte(grantJson), &grant)
+	if err != nil {
+		return nil
+	}
+	grant.rawData = []byte(grantJson)
+	return &grant
+}
+
+// VerifySignature 验证授权去掉proof后紧凑JSON的签名
+func (grant *DelegationGrant) VerifySignature(getDidDocument GetDidDocument) (bool, error) {
+	withoutProof := jsonparser.Delete(grant.rawData, proof)
+	withoutProof, err := compactJson(withoutProof)
+	if err != nil {
+		return false, err
+	}
+	return verifySignature(getDidDocument, grant.Proof, withoutProof)
 }
 
 // VerifiablePresentation VP持有者展示的凭证
@@ -286,6 +398,26 @@ func NewVerifiablePresentation(vpJson string) *VerifiablePresentation {
 	return &vp
 }
 
+// RawCredentials 获取VP中各个VC凭证的原始json，保证VC的签名和哈希按原文验证
+func (vp *VerifiablePresentation) RawCredentials() ([]string, error) {
+	var vcs []string
+	var itemErr error
+	_, err := jsonparser.ArrayEach(vp.rawData, func(value []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if dataType != jsonparser.Object {
+			itemErr = errors.New("invalid verifiableCredential in vp")
+			return
+		}
+		vcs = append(vcs, string(value))
+	}, "verifiableCredential")
+	if err != nil {
+		return nil, err
+	}
+	if itemErr != nil {
+		return nil, itemErr
+	}
+	return vcs, nil
+}
+
 // VerifySignature 验证VP持有者展示的凭证的签名
 func (vp *VerifiablePresentation) VerifySignature(getDidDocument GetDidDocument) (bool, error) {
 	withoutProof := jsonparser.Delete(vp.rawData, proof)
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -198,6 +200,32 @@ func generateVC(user, userName, id, phone, issuer string) string {
 	signedVC, _ := json.Marshal(vc)
 	return string(signedVC)
 }
+
+// generateDelegationGrant 生成delegator签名的链下授权
+func generateDelegationGrant(delegator, delegatee, resource, action, nonce string) string {
+	grant := &DelegationGrant{
+		Delegator: getDid(delegator),
+		Delegatee: getDid(delegatee),
+		Resource:  resource,
+		Action:    action,
+		Nonce:     nonce,
+		Domain:    "chain1/DID",
+		Deadline:  time.Now().Unix() + 600,
+	}
+	payload, _ := json.Marshal(grant)
+	sig, err := getPrivateKey(delegator).Sign(payload)
+	if err != nil {
+		panic(err)
+	}
+	grant.Proof = &Proof{
+		Type:               "SM2Signature",
+		ProofPurpose:       "capabilityDelegation",
+		VerificationMethod: getDid(delegator) + "#keys-1",
+		ProofValue:         base64.StdEncoding.EncodeToString(sig),
+	}
+	grantJson, _ := json.Marshal(grant)
+	return string(grantJson)
+}
 func TestVerifiableCredential_VerifySignature(t *testing.T) {
 	vcJson := generateVC("client1", "张三", "511112198811110011", "13800000000", "issuer")
 	vc := NewVerifiableCredential(vcJson)
@@ -261,6 +289,53 @@ func TestVerifiablePresentation_VerifySignature(t *testing.T) {
 	}
 }
 
+func TestVerifiableCredential_ContentHash(t *testing.T) {
+	vcJson := generateVC("client1", "张三", "511112198811110011", "13800000000", "issuer")
+	hash, err := NewVerifiableCredential(vcJson).ContentHash(defaultHashAlgorithm)
+	assert.NoError(t, err)
+	//key逆序并加入空白后哈希不变
+	var fields map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal([]byte(vcJson), &fields))
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%q : %s", key, fields[key]))
+	}
+	reordered := "{\n  " + strings.Join(parts, ",\n  ") + "\n}"
+	assert.NotEqual(t, vcJson, reordered)
+	reorderedHash, err := NewVerifiableCredential(reordered).ContentHash(defaultHashAlgorithm)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, reorderedHash)
+	//VP中的VC按原文取出
+	vp := NewVerifiablePresentation(generateVP("client1", reordered, "实名登录", "challenge"))
+	rawVcs, err := vp.RawCredentials()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rawVcs))
+	rawHash, err := NewVerifiableCredential(rawVcs[0]).ContentHash(defaultHashAlgorithm)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, rawHash)
+}
+
+func TestCanonicalJson(t *testing.T) {
+	cases := map[string]string{
+		`{"b": 1, "a": [true, null, "x"]}`:            `{"a":[true,null,"x"],"b":1}`,
+		`{"n": [1.0, -0, 1e21, 1e-7, 0.000001, 100]}`: `{"n":[1,0,1e+21,1e-7,0.000001,100]}`,
+		`{"s": "<\u00e9\n\u001f>"}`:                   `{"s":"<é\n\u001f>"}`,
+		`{"\u20ac": 1, "\r": 2, "1": 3}`:              `{"\r":2,"1":3,"€":1}`,
+	}
+	for input, expected := range cases {
+		output, err := canonicalJson([]byte(input))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(output))
+	}
+	_, err := canonicalJson([]byte(`{"a":1} {}`))
+	assert.Error(t, err)
+}
+
 func TestVcTemplateVerify(t *testing.T) {
 	vcTemplate := `{
   "$schema": "http://json-schema.org/draft-07/schema#",
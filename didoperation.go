@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha256"
+	"did/standard"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+// DID所有者签名、由中继者提交的DID操作
+const (
+	didOperationUpdate     = "updateDidDocument"
+	didOperationDeactivate = "deactivate"
+)
+
+// didOperationPayload DID所有者签名的DID操作，签名内容为该结构的紧凑JSON，没有的可选字段省略
+type didOperationPayload struct {
+	// Domain 合约配置的签名域，一般为链ID和合约名称，签名不能在其他链或者合约上重放
+	Domain    string `json:"domain"`
+	Operation string `json:"operation"`
+	Did       string `json:"did"`
+	// DocumentHash 新DID文档紧凑JSON的sha256十六进制哈希，只用于更新
+	DocumentHash string `json:"documentHash,omitempty"`
+	// Nonce 必须是DID上一次签名操作的nonce加1，从1开始
+	Nonce int64 `json:"nonce"`
+	// Expiration 签名的过期时间
+	Expiration int64 `json:"expiration"`
+}
+
+// GetDidNonce 获取DID已经使用的签名操作nonce，下一次签名操作使用该值加1
+func (e *DidContract) GetDidNonce(did string) (int64, error) {
+	return e.dal.getDidNonce(did)
+}
+
+// UpdateDidDocumentWithProof 由中继者提交DID所有者签名的DID文档更新，DID所有者不需要链上账户
+// @param proofJson DID当前文档中的密钥对didOperationPayload紧凑JSON的签名
+func (e *DidContract) UpdateDidDocumentWithProof(didDocument string, nonce int64, expiration int64,
+	proofJson string) error {
+	err := e.checkDocumentSize(didDocument)
+	if err != nil {
+		return err
+	}
+	didDoc := NewDIDDocument(didDocument)
+	if didDoc == nil {
+		return errors.New("invalid did document")
+	}
+	compactDidDoc, err := compactJson([]byte(didDocument))
+	if err != nil {
+		return err
+	}
+	documentHash := sha256.Sum256(compactDidDoc)
+	err = e.checkDidOperationProof(&didOperationPayload{
+		Operation:    didOperationUpdate,
+		Did:          didDoc.ID,
+		DocumentHash: hex.EncodeToString(documentHash[:]),
+		Nonce:        nonce,
+		Expiration:   expiration,
+	}, proofJson)
+	if err != nil {
+		return err
+	}
+	if err = e.updateDidDocument(didDoc, didDocument); err != nil {
+		return err
+	}
+	return e.dal.putDidNonce(didDoc.ID, nonce)
+}
+
+// DeactivateWithProof 由中继者提交DID所有者签名的DID注销，注销后DID不能再更新，密钥不能再用于验证签名，
+// 也不能再注册到其他DID
+// @param proofJson DID当前文档中的密钥对didOperationPayload紧凑JSON的签名
+func (e *DidContract) DeactivateWithProof(did string, nonce int64, expiration int64, proofJson string) error {
+	err := e.checkDidOperationProof(&didOperationPayload{
+		Operation:  didOperationDeactivate,
+		Did:        did,
+		Nonce:      nonce,
+		Expiration: expiration,
+	}, proofJson)
+	if err != nil {
+		return err
+	}
+	//保留公钥和地址索引，注销后的DID不能再作为交易发送者，其密钥和地址也不能再注册到其他DID
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	if err = e.dal.putDidDeactivated(did, myTime); err != nil {
+		return err
+	}
+	if err = e.dal.putDidNonce(did, nonce); err != nil {
+		return err
+	}
+	e.EmitDeactivateDidEvent(did)
+	return nil
+}
+
+// EmitDeactivateDidEvent 发送DID注销事件
+func (e *DidContract) EmitDeactivateDidEvent(did string) {
+	sdk.Instance.EmitEvent(standard.Topic_DeactivateDid, []string{did})
+}
+
+// proofDomain 获取中继提交的签名所绑定的域，未配置时不能执行需要中继签名的操作
+func (e *DidContract) proofDomain() (string, error) {
+	domain := e.dal.getConfig().Domain
+	if len(domain) == 0 {
+		return "", errors.New("proof domain is not configured")
+	}
+	return domain, nil
+}
+
+// checkDidOperationProof 检查DID操作未过期、nonce连续，并且由DID自己的密钥对本合约的签名域签名
+func (e *DidContract) checkDidOperationProof(payload *didOperationPayload, proofJson string) error {
+	deactivated, err := e.dal.isDidDeactivated(payload.Did)
+	if err != nil {
+		return err
+	}
+	if deactivated {
+		return errDidDeactivated
+	}
+	payload.Domain, err = e.proofDomain()
+	if err != nil {
+		return err
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	if payload.Expiration <= myTime {
+		return errors.New("did operation proof is expired")
+	}
+	lastNonce, err := e.dal.getDidNonce(payload.Did)
+	if err != nil {
+		return err
+	}
+	if payload.Nonce != lastNonce+1 {
+		return errors.New("invalid did operation nonce")
+	}
+	var p Proof
+	if err = json.Unmarshal([]byte(proofJson), &p); err != nil {
+		return errors.New("invalid proof")
+	}
+	if err = e.checkProofType(p.Type); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(p.VerificationMethod, payload.Did+"#") {
+		return errors.New("proof is not signed by did")
+	}
+	if err = e.checkVerificationMethodBlackList(p.VerificationMethod); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(payload)
+	pass, err := verifySignature(e.getDidDocument, &p, data)
+	if err != nil {
+		return err
+	}
+	if !pass {
+		return errors.New("invalid did signature")
+	}
+	return nil
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"did/standard"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+// 需要管理员委员会审批的操作
+const (
+	OpSetAdmin                   = "SetAdmin"
+	OpProposeAdmin               = "ProposeAdmin"
+	OpRevokeVc                   = "RevokeVc"
+	OpAddBlackList               = "AddBlackList"
+	OpAddTrustIssuer             = "AddTrustIssuer"
+	OpSetVcTemplate              = "SetVcTemplate"
+	OpSetVcTemplateDraft         = "SetVcTemplateDraft"
+	OpSetVcTemplateWithMetadata  = "SetVcTemplateWithMetadata"
+	OpSetVcTemplateStatus        = "SetVcTemplateStatus"
+	OpSetVcTemplateCompatibility = "SetVcTemplateCompatibility"
+	OpSetTrustRootList           = "SetTrustRootList"
+	OpSetAdminCouncil            = "SetAdminCouncil"
+	OpGrantRole                  = "GrantRole"
+	OpRevokeRole                 = "RevokeRole"
+	OpUnpause                    = "Unpause"
+	OpSetConfig                  = "SetConfig"
+)
+
+// proposalRoles 角色成员可以发起提案的操作，审批门槛大于1时角色成员通过提案执行自己角色的操作
+var proposalRoles = map[string]string{
+	OpRevokeVc:                   RoleRevoker,
+	OpAddBlackList:               RoleBlackListManager,
+	OpAddTrustIssuer:             RoleTrustManager,
+	OpSetTrustRootList:           RoleTrustManager,
+	OpSetVcTemplate:              RoleTemplateManager,
+	OpSetVcTemplateDraft:         RoleTemplateManager,
+	OpSetVcTemplateWithMetadata:  RoleTemplateManager,
+	OpSetVcTemplateStatus:        RoleTemplateManager,
+	OpSetVcTemplateCompatibility: RoleTemplateManager,
+}
+
+// 提案状态
+const (
+	ProposalStatusPending  = "pending"
+	ProposalStatusExecuted = "executed"
+	ProposalStatusRejected = "rejected"
+	ProposalStatusFailed   = "failed"
+)
+
+var errNeedProposal = errors.New("operation requires admin council approval, please use Propose")
+
+// AdminCouncil 管理员委员会，特权操作需要Threshold个管理员审批后才执行
+type AdminCouncil struct {
+	// Admins 管理员DID列表
+	Admins []string `json:"admins"`
+	// Threshold 执行提案需要的审批数量
+	Threshold int `json:"threshold"`
+}
+
+// IsMember 判断did是否是委员会成员
+func (c *AdminCouncil) IsMember(did string) bool {
+	return isInList(did, c.Admins)
+}
+
+// AdminTransfer 未完成的管理员转移
+type AdminTransfer struct {
+	// From 当前管理员DID
+	From string `json:"from"`
+	// To 新管理员DID
+	To string `json:"to"`
+	// ProposeTime 发起时间
+	ProposeTime int64 `json:"proposeTime"`
+	// EffectiveTime 新管理员可以接受的最早时间
+	EffectiveTime int64 `json:"effectiveTime"`
+}
+
+// AdminInfo 合约管理员信息
+type AdminInfo struct {
+	// Admin 当前管理员DID
+	Admin string `json:"admin"`
+	// PendingTransfer 未完成的管理员转移
+	PendingTransfer *AdminTransfer `json:"pendingTransfer,omitempty"`
+	// Council 管理员委员会
+	Council *AdminCouncil `json:"council"`
+}
+
+// Proposal 特权操作提案
+type Proposal struct {
+	// Id 提案ID
+	Id string `json:"id"`
+	// Operation 操作名称
+	Operation string `json:"operation"`
+	// Params 操作参数
+	Params map[string]string `json:"params"`
+	// Proposer 提案人DID
+	Proposer string `json:"proposer"`
+	// Approvals 已审批通过的管理员DID
+	Approvals []string `json:"approvals"`
+	// Rejections 已拒绝的管理员DID
+	Rejections []string `json:"rejections"`
+	// Status 提案状态
+	Status string `json:"status"`
+	// CreateTime 提案时间
+	CreateTime int64 `json:"createTime"`
+	// ExecuteTime 执行时间
+	ExecuteTime int64 `json:"executeTime,omitempty"`
+	// Error 执行失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// GetAdminCouncil 获取管理员委员会，如果没有设置委员会，则默认只有合约管理员一人
+func (e *DidContract) GetAdminCouncil() (*AdminCouncil, error) {
+	council, err := e.dal.getAdminCouncil()
+	if err == nil {
+		return council, nil
+	}
+	adminDid, err := e.dal.getAdmin()
+	if err != nil {
+		return nil, err
+	}
+	return &AdminCouncil{Admins: []string{adminDid}, Threshold: 1}, nil
+}
+
+// SetAdminCouncil 设置管理员委员会
+func (e *DidContract) SetAdminCouncil(admins []string, threshold int) error {
+	if !e.isAdmin() {
+		return errors.New("only admin can set admin council")
+	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
+	if len(admins) == 0 {
+		return errors.New("admin council is empty")
+	}
+	if threshold < 1 || threshold > len(admins) {
+		return errors.New("invalid threshold")
+	}
+	for i, did := range admins {
+		valid, err := e.IsValidDid(did)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errInvalidDid
+		}
+		if isInList(did, admins[:i]) {
+			return errors.New("duplicate admin: " + did)
+		}
+	}
+	council := &AdminCouncil{Admins: admins, Threshold: threshold}
+	err := e.dal.putAdminCouncil(council)
+	if err != nil {
+		return err
+	}
+	e.EmitSetAdminCouncilEvent(council)
+	return nil
+}
+
+// EmitSetAdminCouncilEvent 发送设置管理员委员会事件
+func (e *DidContract) EmitSetAdminCouncilEvent(council *AdminCouncil) {
+	admins, _ := json.Marshal(council.Admins)
+	sdk.Instance.EmitEvent(standard.Topic_SetAdminCouncil, []string{string(admins), strconv.Itoa(council.Threshold)})
+}
+
+// requireProposal 当委员会审批门槛大于1时，特权操作只能通过提案执行
+func (e *DidContract) requireProposal() error {
+	if e.executingProposal {
+		return nil
+	}
+	council, err := e.GetAdminCouncil()
+	if err != nil {
+		return err
+	}
+	if council.Threshold > 1 {
+		return errNeedProposal
+	}
+	return nil
+}
+
+// proposalAction 根据操作名称和参数生成提案执行函数，同时检查参数是否完整
+func (e *DidContract) proposalAction(operation string, params map[string]string) (func() error, error) {
+	switch operation {
+	case OpSetAdmin:
+		did, err := requireParam(params, "did")
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return e.SetAdmin(did) }, nil
+	case OpProposeAdmin:
+		did, err := requireParam(params, "did")
+		if err != nil {
+			return nil, err
+		}
+		delay, _ := strconv.ParseInt(params["delay"], 10, 64)
+		return func() error { return e.ProposeAdmin(did, delay) }, nil
+	case OpRevokeVc:
+		vcID, err := requireParam(params, "vcID")
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return e.RevokeVc(vcID) }, nil
+	case OpAddBlackList:
+		dids, err := requireParam2(params, "did", "dids")
+		if err != nil {
+			return nil, err
+		}
+		expiration, _ := strconv.ParseInt(params["expiration"], 10, 64)
+		return func() error {
+			return e.AddBlackList(dids, params["reason"], params["evidence"], expiration)
+		}, nil
+	case OpAddTrustIssuer:
+		dids, err := requireParam2(params, "did", "dids")
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return e.AddTrustIssuer(dids) }, nil
+	case OpSetVcTemplate, OpSetVcTemplateDraft, OpSetVcTemplateWithMetadata:
+		if err := requireTemplateParams(params); err != nil {
+			return nil, err
+		}
+		if len(params["credentialSchema"]) != 0 {
+			if err := checkTemplateValid(params["credentialSchema"]); err != nil {
+				return nil, errors.New("invalid vc credential schema: " + err.Error())
+			}
+		}
+		if _, err := parseTemplateMetadata(params["metadata"]); err != nil {
+			return nil, err
+		}
+		//SetVcTemplate提案同样可以携带credentialSchema和metadata
+		status := standard.TemplateStatusActive
+		if operation == OpSetVcTemplateDraft {
+			status = standard.TemplateStatusDraft
+		}
+		return func() error {
+			return e.saveVcTemplate(params["id"], params["name"], params["vcType"], params["version"],
+				params["template"], params["credentialSchema"], params["metadata"], status)
+		}, nil
+	case OpSetVcTemplateStatus:
+		for _, key := range []string{"id", "version", "status"} {
+			if _, err := requireParam(params, key); err != nil {
+				return nil, err
+			}
+		}
+		if !isInList(params["status"], templateStatuses) {
+			return nil, errors.New("invalid vc template status: " + params["status"])
+		}
+		return func() error { return e.SetVcTemplateStatus(params["id"], params["version"], params["status"]) }, nil
+	case OpSetVcTemplateCompatibility:
+		for _, key := range []string{"id", "versionRange", "version"} {
+			if _, err := requireParam(params, key); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := parseVersionRange(params["versionRange"]); err != nil {
+			return nil, err
+		}
+		return func() error {
+			return e.SetVcTemplateCompatibility(params["id"], params["versionRange"], params["version"])
+		}, nil
+	case OpSetTrustRootList:
+		dids, err := requireParam2(params, "did", "dids")
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return e.SetTrustRootList(dids) }, nil
+	case OpSetAdminCouncil:
+		var admins []string
+		if err := json.Unmarshal([]byte(params["admins"]), &admins); err != nil {
+			return nil, fmt.Errorf("invalid parameter:'admins', %s", err.Error())
+		}
+		threshold, err := strconv.Atoi(params["threshold"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter:'threshold', %s", err.Error())
+		}
+		return func() error { return e.SetAdminCouncil(admins, threshold) }, nil
+	case OpGrantRole, OpRevokeRole:
+		role, err := requireParam(params, "role")
+		if err != nil {
+			return nil, err
+		}
+		if err = checkRoleValid(role); err != nil {
+			return nil, err
+		}
+		did, err := requireParam(params, "did")
+		if err != nil {
+			return nil, err
+		}
+		if operation == OpGrantRole {
+			return func() error { return e.GrantRole(role, did) }, nil
+		}
+		return func() error { return e.RevokeRole(role, did) }, nil
+	case OpUnpause:
+		var operations []string
+		if err := json.Unmarshal([]byte(params["operations"]), &operations); err != nil {
+			return nil, fmt.Errorf("invalid parameter:'operations', %s", err.Error())
+		}
+		if err := checkPauseOperations(operations); err != nil {
+			return nil, err
+		}
+		return func() error { return e.Unpause(operations) }, nil
+	case OpSetConfig:
+		config, err := requireParam(params, "config")
+		if err != nil {
+			return nil, err
+		}
+		if _, err = e.mergeConfig(config); err != nil {
+			return nil, err
+		}
+		return func() error { return e.SetConfig(config) }, nil
+	}
+	return nil, errors.New("unsupported proposal operation: " + operation)
+}
+
+// Propose 发起特权操作提案，管理员发起时自动审批通过
+// 拥有操作对应角色的成员也可以发起提案，但不计入审批，需要委员会成员审批后执行
+// @param operation 操作名称
+// @param params 操作参数，json对象字符串
+// @return 提案ID
+func (e *DidContract) Propose(operation string, params string) (string, error) {
+	isAdmin := e.isAdmin()
+	role, ok := proposalRoles[operation]
+	if !isAdmin && !(ok && e.senderHasRole(role)) {
+		return "", errors.New("only admin or role member of the operation can propose")
+	}
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return "", err
+	}
+	paramMap := make(map[string]string)
+	if len(params) != 0 {
+		if err = json.Unmarshal([]byte(params), &paramMap); err != nil {
+			return "", errors.New("invalid proposal params: " + err.Error())
+		}
+	}
+	if _, err = e.proposalAction(operation, paramMap); err != nil {
+		return "", err
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return "", err
+	}
+	id, err := e.dal.nextProposalId()
+	if err != nil {
+		return "", err
+	}
+	proposal := &Proposal{
+		Id:         id,
+		Operation:  operation,
+		Params:     paramMap,
+		Proposer:   senderDid,
+		Approvals:  []string{},
+		Rejections: []string{},
+		Status:     ProposalStatusPending,
+		CreateTime: myTime,
+	}
+	if isAdmin {
+		proposal.Approvals = append(proposal.Approvals, senderDid)
+	}
+	e.EmitProposeEvent(proposal)
+	err = e.tryExecuteProposal(proposal)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Approve 审批通过提案，审批数量达到门槛时自动执行
+func (e *DidContract) Approve(proposalId string) error {
+	proposal, senderDid, err := e.getPendingProposalForVote(proposalId)
+	if err != nil {
+		return err
+	}
+	proposal.Approvals = append(proposal.Approvals, senderDid)
+	e.EmitApproveEvent(proposal.Id, senderDid)
+	return e.tryExecuteProposal(proposal)
+}
+
+// Reject 拒绝提案，拒绝数量使提案不可能再达到门槛时，提案被否决
+func (e *DidContract) Reject(proposalId string) error {
+	proposal, senderDid, err := e.getPendingProposalForVote(proposalId)
+	if err != nil {
+		return err
+	}
+	proposal.Rejections = append(proposal.Rejections, senderDid)
+	e.EmitRejectEvent(proposal.Id, senderDid)
+	council, err := e.GetAdminCouncil()
+	if err != nil {
+		return err
+	}
+	//只统计仍然是委员会成员的拒绝
+	if countMembers(council, proposal.Rejections) > len(council.Admins)-council.Threshold {
+		proposal.Status = ProposalStatusRejected
+		e.EmitProposalStatusEvent(proposal)
+	}
+	return e.dal.putProposal(proposal)
+}
+
+// getPendingProposalForVote 获取待审批的提案，并检查sender是否可以投票
+func (e *DidContract) getPendingProposalForVote(proposalId string) (*Proposal, string, error) {
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return nil, "", err
+	}
+	council, err := e.GetAdminCouncil()
+	if err != nil {
+		return nil, "", err
+	}
+	if !council.IsMember(senderDid) {
+		return nil, "", errors.New("only admin council member can vote proposal")
+	}
+	proposal, err := e.dal.getProposal(proposalId)
+	if err != nil {
+		return nil, "", err
+	}
+	if proposal.Status != ProposalStatusPending {
+		return nil, "", errors.New("proposal is " + proposal.Status)
+	}
+	if isInList(senderDid, proposal.Approvals) || isInList(senderDid, proposal.Rejections) {
+		return nil, "", errors.New("admin has already voted")
+	}
+	return proposal, senderDid, nil
+}
+
+// tryExecuteProposal 如果审批数量达到门槛则执行提案，并保存提案
+// 执行失败时提案标记为失败并保存失败原因，不再处于待审批状态，需要重新发起提案
+func (e *DidContract) tryExecuteProposal(proposal *Proposal) error {
+	council, err := e.GetAdminCouncil()
+	if err != nil {
+		return err
+	}
+	//只统计仍然是委员会成员的审批
+	if countMembers(council, proposal.Approvals) >= council.Threshold {
+		//提案的操作名称与合约方法名相同，被暂停的操作不能通过提案执行，恢复后可以重新审批
+		if err = checkMethodPaused(e.dal, proposal.Operation); err != nil {
+			return err
+		}
+		action, err := e.proposalAction(proposal.Operation, proposal.Params)
+		if err != nil {
+			return err
+		}
+		e.executingProposal = true
+		err = action()
+		e.executingProposal = false
+		myTime, err1 := getTxTime()
+		if err1 != nil {
+			return err1
+		}
+		proposal.Status = ProposalStatusExecuted
+		proposal.ExecuteTime = myTime
+		if err != nil {
+			proposal.Status = ProposalStatusFailed
+			proposal.Error = err.Error()
+		}
+		e.EmitProposalStatusEvent(proposal)
+	}
+	return e.dal.putProposal(proposal)
+}
+
+// countMembers 统计dids中仍然是委员会成员的数量
+func countMembers(council *AdminCouncil, dids []string) int {
+	count := 0
+	for _, did := range dids {
+		if council.IsMember(did) {
+			count++
+		}
+	}
+	return count
+}
+
+// GetProposal 获取提案
+func (e *DidContract) GetProposal(proposalId string) (*Proposal, error) {
+	return e.dal.getProposal(proposalId)
+}
+
+// ListProposals 获取提案列表
+// @param status 提案状态，为空则返回所有提案
+func (e *DidContract) ListProposals(status string, cursor string, count int) (*standard.Page[*Proposal], error) {
+	return e.dal.searchProposal(status, cursor, count)
+}
+
+// EmitProposeEvent 发送提案事件
+func (e *DidContract) EmitProposeEvent(proposal *Proposal) {
+	params, _ := json.Marshal(proposal.Params)
+	sdk.Instance.EmitEvent(standard.Topic_Propose, []string{proposal.Id, proposal.Operation, string(params),
+		proposal.Proposer})
+}
+
+// EmitApproveEvent 发送审批通过事件
+func (e *DidContract) EmitApproveEvent(proposalId string, adminDid string) {
+	sdk.Instance.EmitEvent(standard.Topic_Approve, []string{proposalId, adminDid})
+}
+
+// EmitRejectEvent 发送拒绝事件
+func (e *DidContract) EmitRejectEvent(proposalId string, adminDid string) {
+	sdk.Instance.EmitEvent(standard.Topic_Reject, []string{proposalId, adminDid})
+}
+
+// EmitProposalStatusEvent 发送提案状态变更（执行或否决）事件
+func (e *DidContract) EmitProposalStatusEvent(proposal *Proposal) {
+	sdk.Instance.EmitEvent(standard.Topic_ProposalStatus, []string{proposal.Id, proposal.Operation, proposal.Status})
+}
+
+// requireParam 从提案参数中获取必填参数
+func requireParam(params map[string]string, key string) (string, error) {
+	value, ok := params[key]
+	if !ok || len(value) == 0 {
+		return "", fmt.Errorf("CMDID: require proposal parameter:'%s'", key)
+	}
+	return value, nil
+}
+
+// requireTemplateParams 检查设置VC模板的提案参数，credentialSchema、metadata选填
+func requireTemplateParams(params map[string]string) error {
+	for _, key := range []string{"id", "name", "vcType", "version", "template"} {
+		if _, err := requireParam(params, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireParam2 从提案参数中获取key1 单个string或者key2 []string类型参数
+func requireParam2(params map[string]string, key1, key2 string) ([]string, error) {
+	value, ok := params[key2]
+	if !ok || len(value) == 0 {
+		value1, err := requireParam(params, key1)
+		if err != nil {
+			return nil, fmt.Errorf("CMDID: require proposal parameter:'%s' or '%s'", key1, key2)
+		}
+		return []string{value1}, nil
+	}
+	var strs []string
+	err := json.Unmarshal([]byte(value), &strs)
+	if err != nil {
+		return nil, err
+	}
+	return strs, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"did/standard"
+	"encoding/json"
+)
+
+// 增加二级索引的存储版本
+const (
+	// issueLogIndexSchemaVersion 增加VcIssueLog发行者、模板索引
+	issueLogIndexSchemaVersion = 3
+	// templateIndexSchemaVersion 增加VcTemplate类型、所有者索引
+	templateIndexSchemaVersion = 4
+	// delegateIndexSchemaVersion 增加授权的被授权者索引
+	delegateIndexSchemaVersion = 5
+)
+
+// issueLogIndexMigration 为已有的VcIssueLog补建发行者、模板索引和持有人的发行者列表
+// 游标为最后处理的field，下一批从其后继续，重复处理同一条记录只会重写相同的索引
+var issueLogIndexMigration = &Migration{
+	Version:     issueLogIndexSchemaVersion,
+	Description: "vc issue log indexes by issuer and template",
+	Run: func(dal *Dal, cursor string, limit int) (string, int, bool, error) {
+		return migrateByField(dal, keyVcIssueLog, cursor, limit, func(value []byte) error {
+			var vcIssueLog standard.VcIssueLog
+			if err := json.Unmarshal(value, &vcIssueLog); err != nil {
+				return err
+			}
+			return dal.putVcIssueLogIndex(&vcIssueLog, value)
+		})
+	},
+}
+
+// templateIndexMigration 为已有的VcTemplate补建类型、所有者索引，同样以最后处理的field作为游标
+var templateIndexMigration = &Migration{
+	Version:     templateIndexSchemaVersion,
+	Description: "vc template indexes by type and owner",
+	Run: func(dal *Dal, cursor string, limit int) (string, int, bool, error) {
+		return migrateByField(dal, keyVcTemplate, cursor, limit, func(value []byte) error {
+			var vcTemplate standard.VcTemplate
+			if err := json.Unmarshal(value, &vcTemplate); err != nil {
+				return err
+			}
+			return dal.putVcTemplateIndex(&vcTemplate)
+		})
+	},
+}
+
+// delegateIndexMigration 为已有的授权补建被授权者索引
+// 以最后处理的授权field作为游标，授权被撤销不影响下一批从其后继续
+var delegateIndexMigration = &Migration{
+	Version:     delegateIndexSchemaVersion,
+	Description: "delegation index by delegatee",
+	Run: func(dal *Dal, cursor string, limit int) (string, int, bool, error) {
+		return migrateByField(dal, keyDelegate, cursor, limit, func(value []byte) error {
+			var delegate standard.DelegateInfo
+			if err := json.Unmarshal(value, &delegate); err != nil {
+				return err
+			}
+			indexed, err := dal.hasDelegateeIndex(&delegate)
+			if err != nil || indexed {
+				return err
+			}
+			return dal.putDelegateIndex(&delegate, delegateField(&delegate))
+		})
+	},
+}
+
+// migrateByField 按字段顺序处理key下的记录，游标为最后处理的field，每一批直接从游标之后开始遍历
+func migrateByField(dal *Dal, key string, cursor string, limit int, fn func(value []byte) error) (
+	string, int, bool, error) {
+	startField := ""
+	if len(cursor) != 0 {
+		startField = fieldAfter(cursor)
+	}
+	processed := 0
+	more := false
+	err := dal.iterateRange(key, startField, fieldLimit, func(field string, value []byte) error {
+		if processed >= limit {
+			more = true
+			return errStopIteration
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+		cursor = field
+		processed++
+		return nil
+	})
+	if err != nil {
+		return cursor, processed, false, err
+	}
+	if more {
+		return cursor, processed, false, nil
+	}
+	return "", processed, true, nil
+}
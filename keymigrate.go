@@ -0,0 +1,214 @@
+package main
+
+import (
+	"did/standard"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// keyEncodingSchemaVersion 使用encodeKey编码字段的存储版本
+const keyEncodingSchemaVersion = 2
+
+// 旧版本存储使用的key，字段编码有冲突，迁移后数据转存到新的key下
+const (
+	legacyKeyDid             = "d"
+	legacyKeyIndexAddress    = "a"
+	legacyKeyTrustIssuer     = "ti"
+	legacyKeyRevokeVc        = "r"
+	legacyKeyBlackList       = "b"
+	legacyKeyBlackListVm     = "bv"
+	legacyKeyBlackListAddr   = "ba"
+	legacyKeyDelegate        = "g"
+	legacyKeyVcTemplate      = "vt"
+	legacyKeyRole            = "role"
+	legacyKeyVcIssueLog      = "l"
+	legacyKeyVcIndexIssueLog = "vl"
+)
+
+// processDid4Key 旧版本存储的DID字段编码，去掉did:cnbn:并把':'替换为'_'，只用于读取旧数据
+func processDid4Key(did string) string {
+	if len(did) > 9 {
+		did = did[9:] //去掉did:cnbn:
+	}
+	return strings.ReplaceAll(did, ":", "_")
+}
+
+// processVcId 旧版本存储的VC ID字段编码，只用于读取旧数据
+func processVcId(vcID string) string {
+	//vcid 是一个http url，为了存入数据库，需要将其转换为一个只有字母大小写、数字、下划线的字符串
+	vcID = strings.ReplaceAll(vcID, ":", "_")
+	vcID = strings.ReplaceAll(vcID, "/", "_")
+	vcID = strings.ReplaceAll(vcID, ".", "_")
+	vcID = strings.ReplaceAll(vcID, "-", "_")
+	return vcID
+}
+
+// processVm4Key 旧版本存储的验证方法ID（did:cnbn:xxx#keys-1）字段编码，只用于读取旧数据
+func processVm4Key(vm string) string {
+	return strings.ReplaceAll(processDid4Key(vm), "#", "_")
+}
+
+// keyMigrationTable 需要迁移的旧表，newField根据旧记录计算新的key和field
+type keyMigrationTable struct {
+	legacyKey string
+	newField  func(legacyField string, value []byte) (string, string, error)
+}
+
+// keyMigrationTables 按数据量从小到大的顺序迁移，优先迁移模板、信任发行者等配置类数据
+var keyMigrationTables = []*keyMigrationTable{
+	{legacyKeyVcTemplate, func(_ string, value []byte) (string, string, error) {
+		var vcTemplate standard.VcTemplate
+		if err := json.Unmarshal(value, &vcTemplate); err != nil {
+			return "", "", err
+		}
+		return keyVcTemplate, joinKey(vcTemplate.Id, vcTemplate.Version), nil
+	}},
+	{legacyKeyTrustIssuer, func(_ string, value []byte) (string, string, error) {
+		return keyTrustIssuer, encodeKey(string(value)), nil
+	}},
+	{legacyKeyRole, func(legacyField string, value []byte) (string, string, error) {
+		role := strings.TrimSuffix(legacyField, "_"+processDid4Key(string(value)))
+		return keyRole, joinKey(role, string(value)), nil
+	}},
+	{legacyKeyBlackList, migrateBlackListField},
+	{legacyKeyBlackListVm, migrateBlackListField},
+	{legacyKeyBlackListAddr, migrateBlackListField},
+	{legacyKeyRevokeVc, func(_ string, value []byte) (string, string, error) {
+		return keyRevokeVc, encodeKey(string(value)), nil
+	}},
+	{legacyKeyDid, func(_ string, value []byte) (string, string, error) {
+		didDoc := NewDIDDocument(string(value))
+		if didDoc == nil {
+			return "", "", errors.New("invalid did document")
+		}
+		return keyDid, encodeKey(didDoc.ID), nil
+	}},
+	{legacyKeyIndexAddress, func(legacyField string, _ []byte) (string, string, error) {
+		return keyIndexAddress, encodeKey(legacyField), nil
+	}},
+	{legacyKeyDelegate, func(_ string, value []byte) (string, string, error) {
+		var d standard.DelegateInfo
+		if err := json.Unmarshal(value, &d); err != nil {
+			return "", "", err
+		}
+		return keyDelegate, delegateField(&d), nil
+	}},
+	{legacyKeyVcIndexIssueLog, func(_ string, value []byte) (string, string, error) {
+		var vcIssueLog standard.VcIssueLog
+		if err := json.Unmarshal(value, &vcIssueLog); err != nil {
+			return "", "", err
+		}
+		return keyVcIndexIssueLog, joinKey(vcIssueLog.VcID, vcIssueLog.Issuer), nil
+	}},
+	{legacyKeyVcIssueLog, func(_ string, value []byte) (string, string, error) {
+		var vcIssueLog standard.VcIssueLog
+		if err := json.Unmarshal(value, &vcIssueLog); err != nil {
+			return "", "", err
+		}
+		return keyVcIssueLog, vcIssueLogField(vcIssueLog.Did, &vcIssueLog), nil
+	}},
+}
+
+func migrateBlackListField(_ string, value []byte) (string, string, error) {
+	key, field := blackListKey(parseBlackListEntry(value).Did)
+	return key, field, nil
+}
+
+// keyEncodingMigration 将所有字段编码有冲突的旧表迁移为encodeKey编码的新表
+// 每条旧记录转存到新表后删除，所以每批都从旧表头部开始，重复执行不会重复迁移；
+// 新表中已经有的记录是迁移期间新写入的数据，不会被旧数据覆盖
+var keyEncodingMigration = &Migration{
+	Version:     keyEncodingSchemaVersion,
+	Description: "collision-free state key encoding",
+	Run: func(dal *Dal, _ string, limit int) (string, int, bool, error) {
+		processed := 0
+		for _, table := range keyMigrationTables {
+			n, more, err := dal.migrateKeyTable(table, limit-processed)
+			processed += n
+			if err != nil || more {
+				return table.legacyKey, processed, false, err
+			}
+		}
+		return "", processed, true, nil
+	},
+}
+
+// migrateKeyTable 迁移旧表中最多limit条记录，返回迁移的条数以及旧表中是否还有记录
+func (dal *Dal) migrateKeyTable(table *keyMigrationTable, limit int) (int, bool, error) {
+	iter, err := dal.Db().NewIteratorPrefixWithKeyField(table.legacyKey, "")
+	if err != nil {
+		return 0, false, err
+	}
+	defer iter.Close()
+	processed := 0
+	for iter.HasNext() {
+		if processed >= limit {
+			return processed, true, nil
+		}
+		_, legacyField, value, err1 := iter.Next()
+		if err1 != nil {
+			return processed, false, err1
+		}
+		key, field, err1 := table.newField(legacyField, value)
+		if err1 != nil {
+			return processed, false, errors.New("migrate " + table.legacyKey + "#" + legacyField + " failed, " +
+				err1.Error())
+		}
+		dbValue, _ := dal.Db().GetStateByte(key, field)
+		if len(dbValue) == 0 {
+			err1 = dal.Db().PutStateByte(key, field, value)
+			if err1 != nil {
+				return processed, false, err1
+			}
+		}
+		err1 = dal.Db().DelState(table.legacyKey, legacyField)
+		if err1 != nil {
+			return processed, false, err1
+		}
+		processed++
+	}
+	return processed, false, nil
+}
+
+// isLegacySchema 存储迁移是否还没有完成，没有完成时需要同时读取旧表
+func (dal *Dal) isLegacySchema() bool {
+	status, err := dal.getSchemaStatus()
+	if err != nil {
+		//没有保存存储版本时，已有管理员说明是旧合约数据
+		_, err = dal.getAdmin()
+		return err == nil
+	}
+	return status.Version < keyEncodingSchemaVersion
+}
+
+// iterateLegacy 迁移还没有完成时遍历旧表中以legacyPrefix开头的记录，旧表字段编码有冲突，fn需要按记录内容精确过滤
+func (dal *Dal) iterateLegacy(legacyKey, legacyPrefix string, fn func(value []byte) error) error {
+	if !dal.isLegacySchema() {
+		return nil
+	}
+	return dal.iteratePrefix(legacyKey, legacyPrefix, func(_ string, value []byte) error {
+		return fn(value)
+	})
+}
+
+// getStateCompat 读取新表，新表中没有且迁移还没有完成时读取旧表
+func (dal *Dal) getStateCompat(key, field, legacyKey, legacyField string) ([]byte, error) {
+	value, err := dal.Db().GetStateByte(key, field)
+	if err != nil || len(value) != 0 || !dal.isLegacySchema() {
+		return value, err
+	}
+	return dal.Db().GetStateByte(legacyKey, legacyField)
+}
+
+// delStateCompat 删除新表中的记录，迁移还没有完成时同时删除旧表中的记录，防止被迁移回来
+func (dal *Dal) delStateCompat(key, field, legacyKey, legacyField string) error {
+	err := dal.Db().DelState(key, field)
+	if err != nil {
+		return err
+	}
+	if !dal.isLegacySchema() {
+		return nil
+	}
+	return dal.Db().DelState(legacyKey, legacyField)
+}
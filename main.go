@@ -12,13 +12,6 @@ import (
 	"chainmaker.org/chainmaker/contract-utils/safemath"
 )
 
-var (
-	// EnableTrustIssuer 是否启用信任发行者功能
-	EnableTrustIssuer = true
-	// EnableVcIssueLog 是否启用VC凭证发行日志功能
-	EnableVcIssueLog = true
-)
-
 func main() {
 	err := sandbox.Start(&MainContract{c: NewDidContract()})
 	if err != nil {
@@ -32,8 +25,68 @@ type DidContractAll interface {
 	standard.CMDIDOption
 	standard.CMBC
 	InitAdmin(didJson string) error
+	InitConfig(configJson string) error
+	SetConfig(configJson string) error
+	GetConfig() (*ContractConfig, error)
+	RunMigrations(step int) (*SchemaStatus, error)
+	Migrate(step int) (*SchemaStatus, error)
+	GetSchemaStatus() (*SchemaStatus, error)
 	SetAdmin(did string) error
 	GetAdmin() (string, error)
+	GetAdminInfo() (*AdminInfo, error)
+	ProposeAdmin(did string, delay int64) error
+	AcceptAdmin() error
+	CancelAdminTransfer() error
+	GetBlackListEntry(did string) (*standard.BlackListEntry, error)
+	SetAdminCouncil(admins []string, threshold int) error
+	GetAdminCouncil() (*AdminCouncil, error)
+	Propose(operation string, params string) (string, error)
+	Approve(proposalId string) error
+	Reject(proposalId string) error
+	GetProposal(proposalId string) (*Proposal, error)
+	ListProposals(status string, cursor string, count int) (*standard.Page[*Proposal], error)
+	GrantRole(role string, did string) error
+	RevokeRole(role string, did string) error
+	HasRole(role string, did string) (bool, error)
+	GetRoleMembers(role string, cursor string, count int) (*standard.Page[string], error)
+	Pause(operations []string) error
+	Unpause(operations []string) error
+	IsPaused(operation string) (bool, error)
+	GetVcIssueLogsByIssuer(issuer string, startTime int64, endTime int64, cursor string, count int) (
+		*standard.Page[*standard.VcIssueLog], error)
+	GetVcIssueLogsByTemplate(templateId string, startTime int64, endTime int64, cursor string, count int) (
+		*standard.Page[*standard.VcIssueLog], error)
+	VcIssueLogWithProof(issuer string, did string, templateId string, templateVersion string, vcID string,
+		vcHash string, hashAlgorithm string, expiration int64, deadline int64, proofJson string) error
+	GetVcIssueLogByHash(vcHash string) ([]*standard.VcIssueLog, error)
+	GetHolderCredentials(did string, issuer string, templateId string, status string, cursor string, count int) (
+		*standard.Page[*standard.HolderCredential], error)
+	SetVcTemplateDraft(id string, name string, vcType string, version string, template string,
+		credentialSchema string, metadata string) error
+	SetVcTemplateStatus(id string, version string, status string) error
+	EmitSetVcTemplateStatusEvent(id string, version string, status string)
+	GetVcTemplatesByType(vcType string, cursor string, count int) (*standard.Page[*standard.VcTemplate], error)
+	GetVcTemplatesByOwner(owner string, cursor string, count int) (*standard.Page[*standard.VcTemplate], error)
+	GetVcTemplatesByIdPrefix(idPrefix string, cursor string, count int) (*standard.Page[*standard.VcTemplate], error)
+	GetLatestVcTemplate(id string) (*standard.VcTemplate, error)
+	SetVcTemplateCompatibility(id string, versionRange string, version string) error
+	GetVcTemplateCompatibility(id string) ([]*standard.TemplateCompatibility, error)
+	EmitSetVcTemplateCompatibilityEvent(id string, versionRange string, version string)
+	SetVcTemplateWithMetadata(id string, name string, vcType string, version string, template string,
+		credentialSchema string, metadata string) error
+	DelegateCapability(delegateeDid string, resource string, action string, expiration int64,
+		capabilityDelegation bool, parentDelegatorDid string) error
+	EmitDelegateCapabilityEvent(delegatorDid string, delegateeDid string, resource string, action string,
+		start int64, expiration int64, capabilityDelegation bool, parentDelegatorDid string)
+	GetDelegationChain(delegatorDid string, delegateeDid string, resource string, action string) (
+		[]*standard.DelegateInfo, error)
+	DelegateWithProof(grantJson string) error
+	GetDelegationsReceived(delegateeDid string, cursor string, count int) (*standard.Page[*standard.DelegateInfo],
+		error)
+	GetDidNonce(did string) (int64, error)
+	UpdateDidDocumentWithProof(didDocument string, nonce int64, expiration int64, proofJson string) error
+	DeactivateWithProof(did string, nonce int64, expiration int64, proofJson string) error
+	EmitDeactivateDidEvent(did string)
 }
 
 // MainContract 长安链DID主入口合约
@@ -47,15 +100,42 @@ func (e *MainContract) InitContract() protogo.Response {
 	if err != nil {
 		return sdk.Error(err.Error())
 	}
-	return Return(e.c.InitAdmin(adminDidDoc))
+	//新安装的合约直接使用最新的存储版本
+	_, err = e.c.RunMigrations(0)
+	if err != nil {
+		return sdk.Error(err.Error())
+	}
+	err = e.c.InitAdmin(adminDidDoc)
+	if err != nil {
+		return sdk.Error(err.Error())
+	}
+	//安装合约时可以同时设置合约配置，不设置则使用默认配置
+	config := OptionString("config")
+	if len(config) > 0 {
+		return Return(e.c.InitConfig(config))
+	}
+	return sdk.SuccessResponse
 }
 
 // UpgradeContract upgrade contract func
 func (e *MainContract) UpgradeContract() protogo.Response {
+	//升级合约时先迁移旧的存储数据，数据量大时未完成的部分可以通过Migrate继续
+	_, err := e.c.RunMigrations(OptionInt("migrateStep", defaultMigrateStep))
+	if err != nil {
+		return sdk.Error(err.Error())
+	}
 	//在升级合约的时候，可以重新设置新的管理员，也可以不设置
 	adminDidDoc := OptionString("didDocument")
 	if len(adminDidDoc) > 0 {
-		return Return(e.c.InitAdmin(adminDidDoc))
+		err = e.c.InitAdmin(adminDidDoc)
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+	}
+	//升级合约时也可以修改合约配置，只需要传入要修改的字段
+	config := OptionString("config")
+	if len(config) > 0 {
+		return Return(e.c.InitConfig(config))
 	}
 	return sdk.SuccessResponse
 }
@@ -69,6 +149,11 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 		}
 	}()
 
+	// 被暂停的方法直接拒绝，查询方法不受影响
+	if err := checkMethodPaused(&Dal{}, method); err != nil {
+		return sdk.Error(err.Error())
+	}
+
 	switch method {
 	case "SetAdmin":
 		adminDid, err := RequireString("did")
@@ -78,6 +163,127 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 		return Return(e.c.SetAdmin(adminDid))
 	case "GetAdmin":
 		return ReturnString(e.c.GetAdmin())
+	case "GetAdminInfo":
+		return ReturnJson(e.c.GetAdminInfo())
+	case "ProposeAdmin":
+		adminDid, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		delay := OptionTime("delay")
+		return Return(e.c.ProposeAdmin(adminDid, delay))
+	case "AcceptAdmin":
+		return Return(e.c.AcceptAdmin())
+	case "CancelAdminTransfer":
+		return Return(e.c.CancelAdminTransfer())
+	case "SetAdminCouncil":
+		admins, err := RequireStrings("admins")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		threshold := OptionInt("threshold", 1)
+		return Return(e.c.SetAdminCouncil(admins, threshold))
+	case "GetAdminCouncil":
+		return ReturnJson(e.c.GetAdminCouncil())
+	case "Propose":
+		operation, err := RequireString("operation")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		params := OptionString("params")
+		return ReturnString(e.c.Propose(operation, params))
+	case "Approve":
+		proposalId, err := RequireString("proposalId")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.Approve(proposalId))
+	case "Reject":
+		proposalId, err := RequireString("proposalId")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.Reject(proposalId))
+	case "GetProposal":
+		proposalId, err := RequireString("proposalId")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetProposal(proposalId))
+	case "ListProposals":
+		status := OptionString("status")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.ListProposals(status, cursor, count))
+	case "GrantRole":
+		role, err := RequireString("role")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.GrantRole(role, did))
+	case "RevokeRole":
+		role, err := RequireString("role")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.RevokeRole(role, did))
+	case "HasRole":
+		role, err := RequireString("role")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnBool(e.c.HasRole(role, did))
+	case "GetRoleMembers":
+		role, err := RequireString("role")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetRoleMembers(role, cursor, count))
+	case "Pause":
+		operations, err := RequireStrings("operations")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.Pause(operations))
+	case "Unpause":
+		operations, err := RequireStrings("operations")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.Unpause(operations))
+	case "IsPaused":
+		operation, err := RequireString("operation")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnBool(e.c.IsPaused(operation))
+	case "SetConfig":
+		config, err := RequireString("config")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.SetConfig(config))
+	case "GetConfig":
+		return ReturnJson(e.c.GetConfig())
+	case "Migrate":
+		step := OptionInt("step", defaultMigrateStep)
+		return ReturnJson(e.c.Migrate(step))
+	case "GetSchemaStatus":
+		return ReturnJson(e.c.GetSchemaStatus())
 	case "DidMethod":
 		return sdk.Success([]byte(e.c.DidMethod()))
 	case "IsValidDid":
@@ -130,21 +336,66 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 		return Return(e.c.RevokeVc(vcID))
 	case "GetRevokedVcList":
 		vcIDSearch := OptionString("vcIDSearch")
-		start := OptionInt("start", 0)
+		cursor := OptionString("cursor")
 		count := OptionInt("count", 10)
-		return ReturnJson(e.c.GetRevokedVcList(vcIDSearch, start, count))
+		return ReturnJson(e.c.GetRevokedVcList(vcIDSearch, cursor, count))
 	case "UpdateDidDocument":
 		didDocument, err := RequireString("didDocument")
 		if err != nil {
 			return sdk.Error(err.Error())
 		}
 		return Return(e.c.UpdateDidDocument(didDocument))
+	case "UpdateDidDocumentWithProof":
+		didDocument, err := RequireString("didDocument")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		nonce, err := RequireInt64("nonce")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		proof, err := RequireString("proof")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		expiration, err := RequireInt64("expiration")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.UpdateDidDocumentWithProof(didDocument, nonce, expiration, proof))
+	case "DeactivateWithProof":
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		nonce, err := RequireInt64("nonce")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		proof, err := RequireString("proof")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		expiration, err := RequireInt64("expiration")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.DeactivateWithProof(did, nonce, expiration, proof))
+	case "GetDidNonce":
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetDidNonce(did))
 	case "AddBlackList":
 		dids, err := RequireString2("did", "dids")
 		if err != nil {
 			return sdk.Error(err.Error())
 		}
-		return Return(e.c.AddBlackList(dids))
+		reason := OptionString("reason")
+		evidence := OptionString("evidence")
+		expiration := OptionTime("expiration")
+		return Return(e.c.AddBlackList(dids, reason, evidence, expiration))
 	case "DeleteBlackList":
 		dids, err := RequireString2("did", "dids")
 		if err != nil {
@@ -153,9 +404,15 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 		return Return(e.c.DeleteBlackList(dids))
 	case "GetBlackList":
 		didSearch := OptionString("didSearch")
-		start := OptionInt("start", 0)
+		cursor := OptionString("cursor")
 		count := OptionInt("count", 10)
-		return ReturnJson(e.c.GetBlackList(didSearch, start, count))
+		return ReturnJson(e.c.GetBlackList(didSearch, cursor, count))
+	case "GetBlackListEntry":
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetBlackListEntry(did))
 	case "SetTrustRootList":
 		dids, err := RequireString2("did", "dids")
 		if err != nil {
@@ -182,6 +439,50 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 			return sdk.Error(err.Error())
 		}
 		return Return(e.c.Delegate(delegateeDid, resource, action, expiration))
+	case "DelegateCapability":
+		delegateeDid, err := RequireString("delegateeDid")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		action, err := RequireString("action")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		resource := OptionString("resource")
+		expiration := OptionTime("expiration")
+		capabilityDelegation := OptionBool("capabilityDelegation")
+		parentDelegatorDid := OptionString("parentDelegatorDid")
+		return Return(e.c.DelegateCapability(delegateeDid, resource, action, expiration, capabilityDelegation,
+			parentDelegatorDid))
+	case "GetDelegationsReceived":
+		delegateeDid, err := RequireString("delegateeDid")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetDelegationsReceived(delegateeDid, cursor, count))
+	case "DelegateWithProof":
+		grantJson, err := RequireString("grantJson")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.DelegateWithProof(grantJson))
+	case "GetDelegationChain":
+		delegatorDid, err := RequireString("delegatorDid")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		delegateeDid, err := RequireString("delegateeDid")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		action, err := RequireString("action")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		resource := OptionString("resource")
+		return ReturnJson(e.c.GetDelegationChain(delegatorDid, delegateeDid, resource, action))
 	case "RevokeDelegate":
 		delegateeDid, err := RequireString("delegateeDid")
 		if err != nil {
@@ -208,10 +509,10 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 		resource := OptionString("resource")
 		action := OptionString("action")
 
-		start := OptionInt("start", 0)
+		cursor := OptionString("cursor")
 		count := OptionInt("count", 10)
-		return ReturnJson(e.c.GetDelegateList(delegatorDid, delegateeDid, resource, action, start, count))
-	case "SetVcTemplate":
+		return ReturnJson(e.c.GetDelegateList(delegatorDid, delegateeDid, resource, action, cursor, count))
+	case "SetVcTemplate", "SetVcTemplateDraft", "SetVcTemplateWithMetadata":
 		templateId, err := RequireString("id")
 		if err != nil {
 			return sdk.Error(err.Error())
@@ -232,7 +533,32 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 		if err != nil {
 			return sdk.Error(err.Error())
 		}
+		credentialSchema := OptionString("credentialSchema")
+		metadata := OptionString("metadata")
+		if method == "SetVcTemplateDraft" {
+			return Return(e.c.SetVcTemplateDraft(templateId, templateName, vcType, version, vcTemplate,
+				credentialSchema, metadata))
+		}
+		//SetVcTemplate带有credentialSchema或metadata时按SetVcTemplateWithMetadata发布，不丢弃参数
+		if method == "SetVcTemplateWithMetadata" || len(credentialSchema) > 0 || len(metadata) > 0 {
+			return Return(e.c.SetVcTemplateWithMetadata(templateId, templateName, vcType, version, vcTemplate,
+				credentialSchema, metadata))
+		}
 		return Return(e.c.SetVcTemplate(templateId, templateName, vcType, version, vcTemplate))
+	case "SetVcTemplateStatus":
+		templateId, err := RequireString("id")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		version, err := RequireString("version")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		status, err := RequireString("status")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.SetVcTemplateStatus(templateId, version, status))
 	case "GetVcTemplate":
 		templateId, err := RequireString("id")
 		if err != nil {
@@ -243,11 +569,58 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 			return sdk.Error(err.Error())
 		}
 		return ReturnJson(e.c.GetVcTemplate(templateId, version))
+	case "GetVcTemplatesByType":
+		vcType, err := RequireString("vcType")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetVcTemplatesByType(vcType, cursor, count))
+	case "GetVcTemplatesByOwner":
+		owner, err := RequireString("owner")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetVcTemplatesByOwner(owner, cursor, count))
+	case "GetVcTemplatesByIdPrefix":
+		idPrefix := OptionString("idPrefix")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetVcTemplatesByIdPrefix(idPrefix, cursor, count))
+	case "GetLatestVcTemplate":
+		templateId, err := RequireString("id")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetLatestVcTemplate(templateId))
+	case "SetVcTemplateCompatibility":
+		templateId, err := RequireString("id")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		versionRange, err := RequireString("versionRange")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		version, err := RequireString("version")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.SetVcTemplateCompatibility(templateId, versionRange, version))
+	case "GetVcTemplateCompatibility":
+		templateId, err := RequireString("id")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetVcTemplateCompatibility(templateId))
 	case "GetVcTemplateList":
 		nameSearch := OptionString("nameSearch")
-		start := OptionInt("start", 0)
+		cursor := OptionString("cursor")
 		count := OptionInt("count", 10)
-		return ReturnJson(e.c.GetVcTemplateList(nameSearch, start, count))
+		return ReturnJson(e.c.GetVcTemplateList(nameSearch, cursor, count))
 
 	case "Standards":
 		return ReturnJson(e.c.Standards(), nil)
@@ -257,66 +630,127 @@ func (e *MainContract) InvokeContract(method string) (result protogo.Response) {
 			return sdk.Error(err.Error())
 		}
 		return ReturnBool(e.c.SupportStandard(standardName), nil)
-	}
-	if EnableTrustIssuer {
-		switch method {
-		case "AddTrustIssuer":
-			dids, err := RequireString2("did", "dids")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			return Return(e.c.AddTrustIssuer(dids))
-		case "DeleteTrustIssuer":
-			dids, err := RequireString2("did", "dids")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			return Return(e.c.DeleteTrustIssuer(dids))
-		case "GetTrustIssuer":
-			didSearch := OptionString("didSearch")
-			start := OptionInt("start", 0)
-			count := OptionInt("count", 10)
-			return ReturnJson(e.c.GetTrustIssuer(didSearch, start, count))
+	case "AddTrustIssuer":
+		dids, err := RequireString2("did", "dids")
+		if err != nil {
+			return sdk.Error(err.Error())
 		}
-	}
-
-	if EnableVcIssueLog {
-		switch method {
-		case "VcIssueLog":
-			issuer, err := RequireString("issuer")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			did, err := RequireString("did")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			templateID := OptionString("templateID")
-			vcID, err := RequireString("vcID")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			return Return(e.c.VcIssueLog(issuer, did, templateID, vcID))
-		case "GetVcIssueLogs":
-			issuer, err := RequireString("issuer")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			did, err := RequireString("did")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			templateID := OptionString("templateID")
-			start := OptionInt("start", 0)
-			count := OptionInt("count", 10)
-			return ReturnJson(e.c.GetVcIssueLogs(issuer, did, templateID, start, count))
-		case "GetVcIssuers":
-			did, err := RequireString("did")
-			if err != nil {
-				return sdk.Error(err.Error())
-			}
-			return ReturnJson(e.c.GetVcIssuers(did))
+		return Return(e.c.AddTrustIssuer(dids))
+	case "DeleteTrustIssuer":
+		dids, err := RequireString2("did", "dids")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.DeleteTrustIssuer(dids))
+	case "GetTrustIssuer":
+		didSearch := OptionString("didSearch")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetTrustIssuer(didSearch, cursor, count))
+	case "VcIssueLog":
+		issuer, err := RequireString("issuer")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		templateID := OptionString("templateID")
+		templateVersion := OptionString("templateVersion")
+		vcID, err := RequireString("vcID")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		vcHash := OptionString("vcHash")
+		hashAlgorithm := OptionString("hashAlgorithm")
+		expiration := OptionTime("expiration")
+		return Return(e.c.VcIssueLog(issuer, did, templateID, templateVersion, vcID, vcHash, hashAlgorithm,
+			expiration))
+	case "VcIssueLogWithProof":
+		issuer, err := RequireString("issuer")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		templateID := OptionString("templateID")
+		templateVersion := OptionString("templateVersion")
+		vcID, err := RequireString("vcID")
+		if err != nil {
+			return sdk.Error(err.Error())
 		}
+		vcHash := OptionString("vcHash")
+		hashAlgorithm := OptionString("hashAlgorithm")
+		expiration := OptionTime("expiration")
+		deadline, err := RequireInt64("deadline")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		proofJson, err := RequireString("proof")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return Return(e.c.VcIssueLogWithProof(issuer, did, templateID, templateVersion, vcID, vcHash,
+			hashAlgorithm, expiration, deadline, proofJson))
+	case "GetVcIssueLogByHash":
+		vcHash, err := RequireString("vcHash")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetVcIssueLogByHash(vcHash))
+	case "GetHolderCredentials":
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		issuer := OptionString("issuer")
+		templateID := OptionString("templateID")
+		status := OptionString("status")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetHolderCredentials(did, issuer, templateID, status, cursor, count))
+	case "GetVcIssueLogs":
+		issuer, err := RequireString("issuer")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		templateID := OptionString("templateID")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetVcIssueLogs(issuer, did, templateID, cursor, count))
+	case "GetVcIssueLogsByIssuer":
+		issuer, err := RequireString("issuer")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		startTime := OptionTime("startTime")
+		endTime := OptionTime("endTime")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetVcIssueLogsByIssuer(issuer, startTime, endTime, cursor, count))
+	case "GetVcIssueLogsByTemplate":
+		templateID, err := RequireString("templateID")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		startTime := OptionTime("startTime")
+		endTime := OptionTime("endTime")
+		cursor := OptionString("cursor")
+		count := OptionInt("count", 10)
+		return ReturnJson(e.c.GetVcIssueLogsByTemplate(templateID, startTime, endTime, cursor, count))
+	case "GetVcIssuers":
+		did, err := RequireString("did")
+		if err != nil {
+			return sdk.Error(err.Error())
+		}
+		return ReturnJson(e.c.GetVcIssuers(did))
 	}
 
 	return sdk.Error("invalid method:" + method)
@@ -419,6 +853,19 @@ func RequireString(key string) (string, error) {
 	return string(b), nil
 }
 
+// RequireInt64 必须要有参数 int64类型
+func RequireInt64(key string) (int64, error) {
+	s, err := RequireString(key)
+	if err != nil {
+		return 0, err
+	}
+	num, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("CMDID: invalid parameter:'%s'", key)
+	}
+	return num, nil
+}
+
 // RequireStrings 必须要有参数 []string类型
 func RequireStrings(key string) ([]string, error) {
 	args := sdk.Instance.GetArgs()
@@ -522,3 +969,17 @@ func OptionString(key string) string {
 	}
 	return string(b)
 }
+
+// OptionBool 获取可选参数 bool类型，没有或者无法解析则返回false
+func OptionBool(key string) bool {
+	args := sdk.Instance.GetArgs()
+	b, ok := args[key]
+	if !ok {
+		return false
+	}
+	v, err := strconv.ParseBool(string(b))
+	if err != nil {
+		return false
+	}
+	return v
+}
@@ -1,12 +1,12 @@
 package main
 
 import (
+	"did/standard"
 	"reflect"
 	"strings"
 	"testing"
 
 	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
-	"chainmaker.org/chainmaker/contract-utils/standard"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
@@ -79,6 +79,22 @@ func TestInvokeContract(t *testing.T) {
 		"nameSearch":   []byte("XXX"),
 		"standardName": []byte("CMDID"),
 		"vcType":       []byte("ID"),
+		"admins":       []byte(`["userDid"]`),
+		"operation":    []byte("SetAdmin"),
+		"proposalId":   []byte("1"),
+		"role":         []byte("revoker"),
+		"operations":   []byte(`["vc"]`),
+		"config":       []byte(`{"maxPageSize":100}`),
+		"templateID":   []byte("1"),
+		"proof":        []byte("{}"),
+		"vcHash":       []byte("00"),
+		"status":       []byte("active"),
+		"owner":        []byte("userDid"),
+		"versionRange": []byte("^1"),
+		"grantJson":    []byte("{}"),
+		"nonce":        []byte("1"),
+		"expiration":   []byte("1"),
+		"deadline":     []byte("1"),
 	})
 	//sdk.Instance = mockInstance
 	var f = func(method string) {
@@ -93,10 +109,8 @@ func TestInvokeContract(t *testing.T) {
 	methods := GetInterfaceMethods((*DidContractAll)(nil))
 	for _, method := range methods {
 		t.Logf("method:%s", method)
-		if !strings.HasPrefix(method, "Emit") && method != "InitAdmin" {
-			if !EnableVcIssueLog && (method == "VcIssueLog" || method == "GetVcIssueLogs" || method == "GetVcIssuers") {
-				continue
-			}
+		if !strings.HasPrefix(method, "Emit") && method != "InitAdmin" && method != "InitConfig" &&
+			method != "RunMigrations" {
 			f(method)
 		}
 	}
@@ -170,6 +184,45 @@ func GetInterfaceMethods(i interface{}) []string {
 	return methodNames
 }
 
+// TestInvokeContract_SetVcTemplate SetVcTemplate带有credentialSchema和metadata时不能丢弃
+func TestInvokeContract_SetVcTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockInstance := sdk.NewMockSDKInterface(ctrl)
+	mockSdkInstance(mockInstance, t)
+	mockInstance.EXPECT().Origin().AnyTimes().Return(getAddressByName("admin"), nil)
+	args := map[string][]byte{}
+	mockInstance.EXPECT().GetArgs().AnyTimes().DoAndReturn(func() map[string][]byte { return args })
+	mockInstance.EXPECT().Warnf(gomock.Any(), gomock.Any()).AnyTimes()
+	sdk.Instance = mockInstance
+
+	contract := &DidContract{dal: &Dal{}}
+	require.NoError(t, contract.InitAdmin(generateDidDocument("admin", "admin")))
+	mainContract := &MainContract{c: contract}
+	args = map[string][]byte{
+		"id":               []byte("1"),
+		"name":             []byte("个人实名认证"),
+		"vcType":           []byte("ID"),
+		"version":          []byte("1.0.0"),
+		"template":         []byte(`{"type":"object"}`),
+		"credentialSchema": []byte(`{"type":"object","required":["issuer"]}`),
+		"metadata":         []byte(`{"maxValidity":3600}`),
+	}
+	result := mainContract.InvokeContract("SetVcTemplate")
+	require.EqualValues(t, 0, result.Status, result.Message)
+	template, err := contract.GetVcTemplate("1", "1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, standard.TemplateStatusActive, template.Status)
+	require.Equal(t, `{"type":"object","required":["issuer"]}`, template.CredentialSchema)
+	require.NotNil(t, template.Metadata)
+	require.Equal(t, int64(3600), template.Metadata.MaxValidity)
+	//无效的metadata返回错误，而不是忽略
+	args["version"] = []byte("1.0.1")
+	args["metadata"] = []byte("{")
+	result = mainContract.InvokeContract("SetVcTemplate")
+	require.NotEqualValues(t, 0, result.Status)
+}
+
 type mockContractAll struct {
 }
 
@@ -223,7 +276,7 @@ func (m mockContractAll) RevokeVc(vcID string) error {
 	panic("implement me")
 }
 
-func (m mockContractAll) GetRevokedVcList(vcIDSearch string, start int, count int) ([]string, error) {
+func (m mockContractAll) GetRevokedVcList(vcIDSearch string, cursor string, count int) (*standard.Page[string], error) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -238,7 +291,7 @@ func (m mockContractAll) UpdateDidDocument(didDocument string) error {
 	panic("implement me")
 }
 
-func (m mockContractAll) AddBlackList(dids []string) error {
+func (m mockContractAll) AddBlackList(dids []string, reason string, evidence string, expiration int64) error {
 	//TODO implement me
 	panic("implement me")
 }
@@ -248,12 +301,17 @@ func (m mockContractAll) DeleteBlackList(dids []string) error {
 	panic("implement me")
 }
 
-func (m mockContractAll) GetBlackList(didSearch string, start int, count int) ([]string, error) {
+func (m mockContractAll) GetBlackList(didSearch string, cursor string, count int) (*standard.Page[*standard.BlackListEntry], error) {
 	//TODO implement me
 	panic("implement me")
 }
 
-func (m mockContractAll) EmitAddBlackListEvent(dids []string) {
+func (m mockContractAll) GetBlackListEntry(did string) (*standard.BlackListEntry, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) EmitAddBlackListEvent(entries []*standard.BlackListEntry) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -288,7 +346,7 @@ func (m mockContractAll) DeleteTrustIssuer(dids []string) error {
 	panic("implement me")
 }
 
-func (m mockContractAll) GetTrustIssuer(didSearch string, start int, count int) ([]string, error) {
+func (m mockContractAll) GetTrustIssuer(didSearch string, cursor string, count int) (*standard.Page[string], error) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -323,7 +381,7 @@ func (m mockContractAll) EmitRevokeDelegateEvent(delegatorDid string, delegateeD
 	panic("implement me")
 }
 
-func (m mockContractAll) GetDelegateList(delegatorDid, delegateeDid string, resource string, action string, start int, count int) ([]*standard.DelegateInfo, error) {
+func (m mockContractAll) GetDelegateList(delegatorDid, delegateeDid string, resource string, action string, cursor string, count int) (*standard.Page[*standard.DelegateInfo], error) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -338,7 +396,7 @@ func (m mockContractAll) GetVcTemplate(id, version string) (*standard.VcTemplate
 	panic("implement me")
 }
 
-func (m mockContractAll) GetVcTemplateList(nameSearch string, start int, count int) ([]*standard.VcTemplate, error) {
+func (m mockContractAll) GetVcTemplateList(nameSearch string, cursor string, count int) (*standard.Page[*standard.VcTemplate], error) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -348,12 +406,13 @@ func (m mockContractAll) EmitSetVcTemplateEvent(templateID string, templateName
 	panic("implement me")
 }
 
-func (m mockContractAll) VcIssueLog(issuer string, did string, templateID string, vcID string) error {
+func (m mockContractAll) VcIssueLog(issuer string, did string, templateID string, templateVersion string,
+	vcID string, vcHash string, hashAlgorithm string, expiration int64) error {
 	//TODO implement me
 	panic("implement me")
 }
 
-func (m mockContractAll) GetVcIssueLogs(issuer string, did string, templateID string, start int, count int) ([]*standard.VcIssueLog, error) {
+func (m mockContractAll) GetVcIssueLogs(issuer string, did string, templateID string, cursor string, count int) (*standard.Page[*standard.VcIssueLog], error) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -383,6 +442,81 @@ func (m mockContractAll) GetAdmin() (string, error) {
 	panic("implement me")
 }
 
+func (m mockContractAll) GetAdminInfo() (*AdminInfo, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) ProposeAdmin(did string, delay int64) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) AcceptAdmin() error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) CancelAdminTransfer() error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) SetAdminCouncil(admins []string, threshold int) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetAdminCouncil() (*AdminCouncil, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) Propose(operation string, params string) (string, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) Approve(proposalId string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) Reject(proposalId string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetProposal(proposalId string) (*Proposal, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) ListProposals(status string, cursor string, count int) (*standard.Page[*Proposal], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GrantRole(role string, did string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) RevokeRole(role string, did string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) HasRole(role string, did string) (bool, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetRoleMembers(role string, cursor string, count int) (*standard.Page[string], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (m mockContractAll) Standards() []string {
 	//TODO implement me
 	panic("implement me")
@@ -394,3 +528,188 @@ func (m mockContractAll) SupportStandard(standardName string) bool {
 }
 
 var _ DidContractAll = (*mockContractAll)(nil)
+
+func (m mockContractAll) Pause(operations []string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) Unpause(operations []string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) IsPaused(operation string) (bool, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) InitConfig(configJson string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) SetConfig(configJson string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetConfig() (*ContractConfig, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) RunMigrations(step int) (*SchemaStatus, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) Migrate(step int) (*SchemaStatus, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetSchemaStatus() (*SchemaStatus, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcIssueLogsByIssuer(issuer string, startTime int64, endTime int64, cursor string,
+	count int) (*standard.Page[*standard.VcIssueLog], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcIssueLogsByTemplate(templateId string, startTime int64, endTime int64, cursor string,
+	count int) (*standard.Page[*standard.VcIssueLog], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) VcIssueLogWithProof(issuer string, did string, templateId string,
+	templateVersion string, vcID string, vcHash string, hashAlgorithm string, expiration int64, deadline int64,
+	proofJson string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcIssueLogByHash(vcHash string) ([]*standard.VcIssueLog, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetHolderCredentials(did string, issuer string, templateId string, status string,
+	cursor string, count int) (*standard.Page[*standard.HolderCredential], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) SetVcTemplateDraft(id string, name string, vcType string, version string,
+	template string, credentialSchema string, metadata string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) SetVcTemplateStatus(id string, version string, status string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) EmitSetVcTemplateStatusEvent(id string, version string, status string) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcTemplatesByType(vcType string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcTemplatesByOwner(owner string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcTemplatesByIdPrefix(idPrefix string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetLatestVcTemplate(id string) (*standard.VcTemplate, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) SetVcTemplateCompatibility(id string, versionRange string, version string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetVcTemplateCompatibility(id string) ([]*standard.TemplateCompatibility, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) EmitSetVcTemplateCompatibilityEvent(id string, versionRange string, version string) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) SetVcTemplateWithMetadata(id string, name string, vcType string, version string,
+	template string, credentialSchema string, metadata string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) DelegateCapability(delegateeDid string, resource string, action string, expiration int64,
+	capabilityDelegation bool, parentDelegatorDid string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) EmitDelegateCapabilityEvent(delegatorDid string, delegateeDid string, resource string,
+	action string, start int64, expiration int64, capabilityDelegation bool, parentDelegatorDid string) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetDelegationChain(delegatorDid string, delegateeDid string, resource string,
+	action string) ([]*standard.DelegateInfo, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) DelegateWithProof(grantJson string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetDelegationsReceived(delegateeDid string, cursor string, count int) (
+	*standard.Page[*standard.DelegateInfo], error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) GetDidNonce(did string) (int64, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) UpdateDidDocumentWithProof(didDocument string, nonce int64, expiration int64,
+	proofJson string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) DeactivateWithProof(did string, nonce int64, expiration int64, proofJson string) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m mockContractAll) EmitDeactivateDidEvent(did string) {
+	//TODO implement me
+	panic("implement me")
+}
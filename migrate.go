@@ -0,0 +1,157 @@
+package main
+
+import (
+	"did/standard"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+const (
+	// legacySchemaVersion 没有保存存储版本的旧合约数据的版本
+	legacySchemaVersion = 1
+	// defaultMigrateStep 每次迁移默认处理的最大记录数
+	defaultMigrateStep = 100
+)
+
+// Migration 存储结构迁移，将数据从Version-1版本迁移到Version版本
+type Migration struct {
+	// Version 迁移完成后的存储版本
+	Version int
+	// Description 迁移说明
+	Description string
+	// Run 执行一批迁移，最多处理limit条记录，cursor为上一批返回的游标，
+	// 返回下一批的游标、本批处理的记录数以及是否已经全部完成。
+	// 迁移必须是幂等的，同一批重复执行不能产生错误数据
+	Run func(dal *Dal, cursor string, limit int) (nextCursor string, processed int, done bool, err error)
+}
+
+// migrations 已注册的迁移，按Version从小到大排列
+var migrations = []*Migration{keyEncodingMigration, issueLogIndexMigration, templateIndexMigration,
+	delegateIndexMigration}
+
+// errMigrationPending 存储迁移完成前，新表和索引中的数据还不完整，列表查询返回该错误
+var errMigrationPending = errors.New("storage migration is pending, please call Migrate")
+
+// currentSchemaVersion 当前合约代码使用的存储版本
+func currentSchemaVersion() int {
+	if len(migrations) == 0 {
+		return legacySchemaVersion
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaStatus 存储版本和迁移进度
+type SchemaStatus struct {
+	// Version 已完成迁移的存储版本
+	Version int `json:"version"`
+	// TargetVersion 当前合约代码需要的存储版本
+	TargetVersion int `json:"targetVersion"`
+	// Cursor 正在进行的迁移的游标
+	Cursor string `json:"cursor,omitempty"`
+	// Processed 正在进行的迁移已处理的记录数
+	Processed int `json:"processed"`
+	// Done 是否已经完成所有迁移
+	Done bool `json:"done"`
+}
+
+func getMigration(version int) *Migration {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m
+		}
+	}
+	return nil
+}
+
+// schemaStatus 获取存储版本，没有保存版本时，已有管理员说明是旧合约数据，否则是新安装的合约
+func (e *DidContract) schemaStatus() *SchemaStatus {
+	status, err := e.dal.getSchemaStatus()
+	if err != nil {
+		status = &SchemaStatus{Version: currentSchemaVersion()}
+		if _, err = e.dal.getAdmin(); err == nil {
+			status.Version = legacySchemaVersion
+		}
+	}
+	status.TargetVersion = currentSchemaVersion()
+	status.Done = status.Version == status.TargetVersion
+	return status
+}
+
+// checkMigrated 列表查询只遍历迁移后的新表和索引，迁移完成前返回errMigrationPending，避免返回不完整的列表
+func (dal *Dal) checkMigrated() error {
+	status, err := dal.getSchemaStatus()
+	if err != nil {
+		//没有保存存储版本时，已有管理员说明是旧合约数据
+		if _, err = dal.getAdmin(); err == nil {
+			return errMigrationPending
+		}
+		return nil
+	}
+	if status.Version < currentSchemaVersion() {
+		return errMigrationPending
+	}
+	return nil
+}
+
+// RunMigrations 执行存储迁移，安装、升级合约时调用，不检查权限
+// @param step 本次最多处理的记录数，未完成的部分可以通过Migrate继续
+func (e *DidContract) RunMigrations(step int) (*SchemaStatus, error) {
+	if step <= 0 {
+		step = defaultMigrateStep
+	}
+	status := e.schemaStatus()
+	if status.Version > status.TargetVersion {
+		return nil, fmt.Errorf("schema version %d is newer than contract version %d",
+			status.Version, status.TargetVersion)
+	}
+	for step > 0 && status.Version < status.TargetVersion {
+		m := getMigration(status.Version + 1)
+		if m == nil {
+			return nil, fmt.Errorf("migration to schema version %d not found", status.Version+1)
+		}
+		nextCursor, processed, done, err := m.Run(e.dal, status.Cursor, step)
+		if err != nil {
+			return nil, fmt.Errorf("migrate to schema version %d failed, %s", m.Version, err.Error())
+		}
+		step -= processed
+		status.Processed += processed
+		status.Cursor = nextCursor
+		if done {
+			status.Version = m.Version
+			status.Cursor = ""
+			status.Processed = 0
+		} else if processed == 0 {
+			return nil, fmt.Errorf("migration to schema version %d made no progress", m.Version)
+		}
+	}
+	status.Done = status.Version == status.TargetVersion
+	err := e.dal.putSchemaStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	e.EmitMigrateEvent(status)
+	return status, nil
+}
+
+// Migrate 管理员分批执行存储迁移，已完成时重复调用不会有任何修改
+// @param step 本次最多处理的记录数
+func (e *DidContract) Migrate(step int) (*SchemaStatus, error) {
+	if !e.isAdmin() {
+		return nil, errors.New("only admin can migrate")
+	}
+	return e.RunMigrations(step)
+}
+
+// GetSchemaStatus 获取存储版本和迁移进度
+func (e *DidContract) GetSchemaStatus() (*SchemaStatus, error) {
+	return e.schemaStatus(), nil
+}
+
+// EmitMigrateEvent 发送存储迁移事件
+func (e *DidContract) EmitMigrateEvent(status *SchemaStatus) {
+	sdk.Instance.EmitEvent(standard.Topic_Migrate, []string{strconv.Itoa(status.Version),
+		strconv.Itoa(status.TargetVersion), status.Cursor, strconv.Itoa(status.Processed)})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"did/standard"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor 将当前页最后一条数据的状态key和field编码为游标
+func encodeCursor(key, field string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + "#" + field))
+}
+
+// decodeCursor 解析游标，空游标表示从头开始
+func decodeCursor(cursor string) (string, string, error) {
+	if len(cursor) == 0 {
+		return "", "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", errInvalidCursor
+	}
+	parts := strings.SplitN(string(b), "#", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return "", "", errInvalidCursor
+	}
+	return parts[0], parts[1], nil
+}
+
+// pager 统一的分页查询，游标为上一页最后一条数据的状态key和field，下一页从该位置之后继续遍历，
+// 翻页期间新增、删除数据不会导致重复或者遗漏。只有配置了pageTotal时才遍历所有数据统计总数
+type pager[T any] struct {
+	dal *Dal
+	// afterKey、afterField 游标位置，started表示已经遍历到游标之后
+	afterKey   string
+	afterField string
+	started    bool
+	count      int
+	withTotal  bool
+	total      int
+	items      []T
+	// key、field 当前遍历到的位置
+	key   string
+	field string
+	// lastKey、lastField 当前页最后一条数据的位置
+	lastKey   string
+	lastField string
+	// more 当前页之后是否还有数据
+	more bool
+}
+
+// newPager 创建分页查询，count未指定或者超过配置的最大值时使用最大值
+func newPager[T any](dal *Dal, cursor string, count int) (*pager[T], error) {
+	afterKey, afterField, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &pager[T]{
+		dal:        dal,
+		afterKey:   afterKey,
+		afterField: afterField,
+		started:    len(cursor) == 0,
+		count:      dal.pageSize(count),
+		withTotal:  dal.getConfig().PageTotal,
+		items:      make([]T, 0),
+	}, nil
+}
+
+// done 不统计总数时，当前页已满并且确认还有下一页后不需要继续遍历
+func (p *pager[T]) done() bool {
+	return p.more && !p.withTotal
+}
+
+// iterate 按字段顺序遍历key下以prefix开头的数据，fn中调用add添加符合条件的数据
+func (p *pager[T]) iterate(key, prefix string, fn func(field string, value []byte) error) error {
+	return p.iterateRange(key, prefix, prefix+fieldLimit, fn)
+}
+
+// iterateRange 按字段顺序遍历key下[startField, limitField)范围内的数据，fn中调用add添加符合条件的数据。
+// 多次调用时依次遍历多张表，游标所在的表之前的表已经返回过，不统计总数时直接跳过，游标所在的表从游标之后继续遍历
+func (p *pager[T]) iterateRange(key, startField, limitField string, fn func(field string, value []byte) error) error {
+	if p.done() {
+		return nil
+	}
+	visit := func(field string, value []byte) error {
+		p.seek(key, field)
+		if err := fn(field, value); err != nil {
+			return err
+		}
+		if p.done() {
+			return errStopIteration
+		}
+		return nil
+	}
+	if !p.started && !p.withTotal {
+		if key != p.afterKey {
+			return nil
+		}
+		if after := fieldAfter(p.afterField); after > startField {
+			startField = after
+		}
+	}
+	err := p.dal.iterateRange(key, startField, limitField, visit)
+	if key == p.afterKey {
+		p.started = true
+	}
+	return err
+}
+
+// seek 设置当前遍历到的位置，不是从状态数据库中遍历的数据通过seek指定排序用的位置
+func (p *pager[T]) seek(key, field string) {
+	p.key, p.field = key, field
+	if !p.started && key == p.afterKey && field > p.afterField {
+		p.started = true
+	}
+}
+
+// add 在当前位置添加一条符合条件的数据，游标之前的数据只计入总数
+func (p *pager[T]) add(item T) {
+	p.total++
+	if !p.started {
+		return
+	}
+	if len(p.items) < p.count {
+		p.items = append(p.items, item)
+		p.lastKey, p.lastField = p.key, p.field
+		return
+	}
+	p.more = true
+}
+
+// page 返回分页结果
+func (p *pager[T]) page() *standard.Page[T] {
+	result := &standard.Page[T]{Items: p.items}
+	if p.more {
+		result.NextCursor = encodeCursor(p.lastKey, p.lastField)
+	}
+	if p.withTotal {
+		total := p.total
+		result.Total = &total
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"did/standard"
+	"errors"
+	"strings"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+// 可暂停的操作分组，查询类方法不会被暂停
+// 撤销VC、添加黑名单、撤销委托、删除信任发行者是应急处置需要的操作，不会被暂停
+const (
+	// PauseAll 暂停所有可暂停的方法
+	PauseAll = "all"
+	// PauseDid DID文档的添加、更新和注销
+	PauseDid = "did"
+	// PauseVc VC发行日志
+	PauseVc = "vc"
+	// PauseVerify VC、VP验证
+	PauseVerify = "verify"
+	// PauseTemplate VC模板设置
+	PauseTemplate = "template"
+	// PauseDelegate 委托
+	PauseDelegate = "delegate"
+	// PauseBlackList 黑名单的删除
+	PauseBlackList = "blacklist"
+	// PauseTrust 信任根的设置和信任发行者的添加
+	PauseTrust = "trust"
+)
+
+// pauseGroups 操作分组与合约方法的对应关系，通过提案执行的操作同样检查
+var pauseGroups = map[string][]string{
+	PauseDid:    {"AddDidDocument", "UpdateDidDocument", "UpdateDidDocumentWithProof", "DeactivateWithProof"},
+	PauseVc:     {"VcIssueLog", "VcIssueLogWithProof"},
+	PauseVerify: {"VerifyVc", "VerifyVp"},
+	PauseTemplate: {"SetVcTemplate", "SetVcTemplateDraft", "SetVcTemplateWithMetadata", "SetVcTemplateStatus",
+		"SetVcTemplateCompatibility"},
+	PauseDelegate:  {"Delegate", "DelegateCapability", "DelegateWithProof"},
+	PauseBlackList: {"DeleteBlackList"},
+	PauseTrust:     {"SetTrustRootList", "AddTrustIssuer"},
+}
+
+// getPauseGroup 获取合约方法所属的操作分组，不可暂停的方法返回空字符串
+func getPauseGroup(method string) string {
+	for group, methods := range pauseGroups {
+		if isInList(method, methods) {
+			return group
+		}
+	}
+	return ""
+}
+
+func checkPauseOperations(operations []string) error {
+	if len(operations) == 0 {
+		return errors.New("operations is empty")
+	}
+	for _, op := range operations {
+		if _, ok := pauseGroups[op]; !ok && op != PauseAll {
+			return errors.New("invalid pause operation: " + op)
+		}
+	}
+	return nil
+}
+
+// checkMethodPaused 检查合约方法是否被暂停，被暂停则返回错误
+func checkMethodPaused(dal *Dal, method string) error {
+	group := getPauseGroup(method)
+	if len(group) == 0 {
+		return nil
+	}
+	if dal.isPaused(PauseAll) || dal.isPaused(group) {
+		return errors.New("method " + method + " is paused, operation group: " + group)
+	}
+	return nil
+}
+
+// Pause 暂停操作，紧急情况下任意一个管理员即可暂停，不需要提案
+// @param operations 操作分组列表，all表示全局暂停
+func (e *DidContract) Pause(operations []string) error {
+	if !e.isAdmin() {
+		return errors.New("only admin can pause")
+	}
+	if err := checkPauseOperations(operations); err != nil {
+		return err
+	}
+	for _, op := range operations {
+		if err := e.dal.putPause(op); err != nil {
+			return err
+		}
+	}
+	e.EmitPauseEvent(operations)
+	return nil
+}
+
+// Unpause 恢复被暂停的操作，设置了管理员委员会时需要提案审批
+// @param operations 操作分组列表，all表示解除全局暂停
+func (e *DidContract) Unpause(operations []string) error {
+	if !e.isAdmin() {
+		return errors.New("only admin can unpause")
+	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
+	if err := checkPauseOperations(operations); err != nil {
+		return err
+	}
+	for _, op := range operations {
+		if err := e.dal.deletePause(op); err != nil {
+			return err
+		}
+	}
+	e.EmitUnpauseEvent(operations)
+	return nil
+}
+
+// IsPaused 查询操作是否被暂停
+// @param operation 操作分组或者合约方法名
+func (e *DidContract) IsPaused(operation string) (bool, error) {
+	if operation == PauseAll {
+		return e.dal.isPaused(PauseAll), nil
+	}
+	if _, ok := pauseGroups[operation]; ok {
+		return e.dal.isPaused(PauseAll) || e.dal.isPaused(operation), nil
+	}
+	group := getPauseGroup(operation)
+	if len(group) == 0 {
+		return false, errors.New("invalid pause operation: " + operation)
+	}
+	return e.dal.isPaused(PauseAll) || e.dal.isPaused(group), nil
+}
+
+// EmitPauseEvent 发送暂停事件
+func (e *DidContract) EmitPauseEvent(operations []string) {
+	sdk.Instance.EmitEvent(standard.Topic_Pause, []string{strings.Join(operations, ",")})
+}
+
+// EmitUnpauseEvent 发送恢复事件
+func (e *DidContract) EmitUnpauseEvent(operations []string) {
+	sdk.Instance.EmitEvent(standard.Topic_Unpause, []string{strings.Join(operations, ",")})
+}
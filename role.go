@@ -0,0 +1,125 @@
+package main
+
+import (
+	"did/standard"
+	"errors"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+)
+
+// 合约角色，管理员是所有角色的管理者，并默认拥有所有角色的权限
+const (
+	// RoleRegistrar 注册员，可以代替DID持有者更新DID文档
+	RoleRegistrar = "registrar"
+	// RoleRevoker 撤销员，可以撤销VC
+	RoleRevoker = "revoker"
+	// RoleTemplateManager 模板管理员，可以设置VC模板
+	RoleTemplateManager = "template-manager"
+	// RoleBlackListManager 黑名单管理员，可以添加、删除黑名单
+	RoleBlackListManager = "blacklist-manager"
+	// RoleTrustManager 信任管理员，可以设置信任根列表和信任发行者
+	RoleTrustManager = "trust-manager"
+)
+
+var roles = []string{RoleRegistrar, RoleRevoker, RoleTemplateManager, RoleBlackListManager, RoleTrustManager}
+
+func checkRoleValid(role string) error {
+	if !isInList(role, roles) {
+		return errors.New("invalid role: " + role)
+	}
+	return nil
+}
+
+// GrantRole 授予did角色
+func (e *DidContract) GrantRole(role string, did string) error {
+	if !e.isAdmin() {
+		return errors.New("only admin can grant role")
+	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
+	if err := checkRoleValid(role); err != nil {
+		return err
+	}
+	valid, err := e.IsValidDid(did)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errInvalidDid
+	}
+	err = e.dal.putRole(role, did)
+	if err != nil {
+		return err
+	}
+	e.EmitGrantRoleEvent(role, did)
+	return nil
+}
+
+// RevokeRole 撤销did的角色
+func (e *DidContract) RevokeRole(role string, did string) error {
+	if !e.isAdmin() {
+		return errors.New("only admin can revoke role")
+	}
+	if err := e.requireProposal(); err != nil {
+		return err
+	}
+	if err := checkRoleValid(role); err != nil {
+		return err
+	}
+	err := e.dal.deleteRole(role, did)
+	if err != nil {
+		return err
+	}
+	e.EmitRevokeRoleEvent(role, did)
+	return nil
+}
+
+// HasRole 判断did是否拥有角色
+func (e *DidContract) HasRole(role string, did string) (bool, error) {
+	if err := checkRoleValid(role); err != nil {
+		return false, err
+	}
+	return e.dal.hasRole(role, did), nil
+}
+
+// GetRoleMembers 获取角色成员列表
+func (e *DidContract) GetRoleMembers(role string, cursor string, count int) (*standard.Page[string], error) {
+	if err := checkRoleValid(role); err != nil {
+		return nil, err
+	}
+	return e.dal.searchRoleMembers(role, cursor, count)
+}
+
+// EmitGrantRoleEvent 发送授予角色事件
+func (e *DidContract) EmitGrantRoleEvent(role string, did string) {
+	sdk.Instance.EmitEvent(standard.Topic_GrantRole, []string{role, did})
+}
+
+// EmitRevokeRoleEvent 发送撤销角色事件
+func (e *DidContract) EmitRevokeRoleEvent(role string, did string) {
+	sdk.Instance.EmitEvent(standard.Topic_RevokeRole, []string{role, did})
+}
+
+// senderHasRole 判断sender是否拥有角色
+func (e *DidContract) senderHasRole(role string) bool {
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return false
+	}
+	return e.dal.hasRole(role, senderDid)
+}
+
+// checkPermission 检查sender是否拥有角色或者是管理员，执行需要审批的操作时，角色成员和管理员一样要通过提案执行，
+// 单个角色成员的密钥不能绕过委员会的审批门槛，角色成员可以对自己角色的操作发起提案，见proposalRoles
+// @param role 操作对应的角色
+// @param needProposal 是否是需要管理员委员会审批的操作
+func (e *DidContract) checkPermission(role string, needProposal bool) error {
+	if !e.senderHasRole(role) && !e.isAdmin() {
+		return errors.New("only admin or " + role + " can do this operation")
+	}
+	if needProposal {
+		return e.requireProposal()
+	}
+	return nil
+}
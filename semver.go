@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versionLatest 模板版本查询，表示最新的可签发版本
+const versionLatest = "latest"
+
+var errInvalidSemVersion = errors.New("invalid semantic version")
+
+// semVersion 语义化版本，支持省略前缀v以及省略次版本号、修订号，例如v1、1.2、1.2.3-beta
+type semVersion struct {
+	major, minor, patch int
+	pre                 string
+	// parts 版本号中实际写出的数字个数
+	parts int
+}
+
+func parseSemVersion(version string) (*semVersion, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+	v := &semVersion{}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.pre = s[i+1:]
+		s = s[:i]
+		if !isValidPrerelease(v.pre) {
+			return nil, errInvalidSemVersion
+		}
+	}
+	nums := strings.Split(s, ".")
+	if len(nums) > 3 {
+		return nil, errInvalidSemVersion
+	}
+	for i, num := range nums {
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 0 || len(num) == 0 || num[0] == '+' {
+			return nil, errInvalidSemVersion
+		}
+		switch i {
+		case 0:
+			v.major = n
+		case 1:
+			v.minor = n
+		case 2:
+			v.patch = n
+		}
+	}
+	v.parts = len(nums)
+	return v, nil
+}
+
+// compare 比较两个版本，预发布版本小于对应的正式版本
+func (v *semVersion) compare(o *semVersion) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return d
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+	return comparePrerelease(v.pre, o.pre)
+}
+
+// String 返回规范化的MAJOR.MINOR.PATCH[-PRERELEASE]形式，省略的次版本号、修订号补0
+func (v *semVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if len(v.pre) != 0 {
+		s += "-" + v.pre
+	}
+	return s
+}
+
+// normalizeTemplateVersion 规范化模板版本，1.2、v1.2和1.2.0是同一个版本；
+// 不是语义化版本的旧版本号原样返回，只能按原值精确匹配
+func normalizeTemplateVersion(version string) string {
+	v, err := parseSemVersion(version)
+	if err != nil {
+		return version
+	}
+	return v.String()
+}
+
+// isValidPrerelease 检查预发布版本：点分隔的标识符非空，只包含[0-9A-Za-z-]，数字标识符不能有前导0
+func isValidPrerelease(pre string) bool {
+	for _, id := range strings.Split(pre, ".") {
+		if len(id) == 0 {
+			return false
+		}
+		for _, c := range id {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+				return false
+			}
+		}
+		if isNumericIdentifier(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumericIdentifier(id string) bool {
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(id) != 0
+}
+
+// comparePrerelease 按SemVer §11逐个比较预发布标识符：数字标识符按数值比较且小于非数字标识符，
+// 非数字标识符按ASCII比较，前面的标识符都相同时标识符少的版本更小
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		aNum, bNum := isNumericIdentifier(as[i]), isNumericIdentifier(bs[i])
+		switch {
+		case aNum && bNum:
+			//去掉前导0后按长度和字典序比较，避免大数溢出
+			x, y := strings.TrimLeft(as[i], "0"), strings.TrimLeft(bs[i], "0")
+			if len(x) != len(y) {
+				return len(x) - len(y)
+			}
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		case aNum:
+			return -1
+		case bNum:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// versionRange 版本范围[min, max)，max为空表示没有上限；exact表示只匹配min
+type versionRange struct {
+	min, max *semVersion
+	exact    bool
+}
+
+// isVersionQuery 判断模板版本是否是需要解析的查询，而不是具体版本
+func isVersionQuery(version string) bool {
+	return version == versionLatest || version == "*" || strings.HasPrefix(version, "^") ||
+		strings.HasPrefix(version, "~")
+}
+
+// parseVersionRange 解析版本范围，支持latest、*、^1.2（兼容主版本）、~1.2（兼容次版本）以及具体版本
+func parseVersionRange(r string) (*versionRange, error) {
+	if r == versionLatest || r == "*" {
+		return &versionRange{min: &semVersion{}}, nil
+	}
+	if !strings.HasPrefix(r, "^") && !strings.HasPrefix(r, "~") {
+		v, err := parseSemVersion(r)
+		if err != nil {
+			return nil, err
+		}
+		return &versionRange{min: v, exact: true}, nil
+	}
+	v, err := parseSemVersion(r[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(v.pre) != 0 {
+		return nil, errInvalidSemVersion
+	}
+	max := &semVersion{}
+	switch {
+	case r[0] == '~' && v.parts > 1, r[0] == '^' && v.major == 0 && (v.minor > 0 || v.parts == 2):
+		max.major, max.minor = v.major, v.minor+1
+	case r[0] == '^' && v.major == 0 && v.minor == 0 && v.parts == 3:
+		max.major, max.minor, max.patch = 0, 0, v.patch+1
+	default:
+		max.major = v.major + 1
+	}
+	return &versionRange{min: v, max: max}, nil
+}
+
+// contains 判断版本是否在范围内，范围查询不匹配预发布版本
+func (r *versionRange) contains(v *semVersion) bool {
+	if r.exact {
+		return v.compare(r.min) == 0
+	}
+	if len(v.pre) != 0 {
+		return false
+	}
+	return v.compare(r.min) >= 0 && (r.max == nil || v.compare(r.max) < 0)
+}
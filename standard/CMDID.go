@@ -8,17 +8,35 @@
 package standard
 
 const (
-	Topic_SetDidDocument    = "SetDidDocument"
-	Topic_SetTrustRootList  = "SetTrustRootList"
-	Topic_RevokeVc          = "RevokeVc"
-	Topic_AddBlackList      = "AddBlackList"
-	Topic_DeleteBlackList   = "DeleteBlackList"
-	Topic_AddTrustIssuer    = "AddTrustIssuer"
-	Topic_DeleteTrustIssuer = "DeleteTrustIssuer"
-	Topic_Delegate          = "Delegate"
-	Topic_RevokeDelegate    = "RevokeDelegate"
-	Topic_SetVcTemplate     = "SetVcTemplate"
-	Topic_VcIssueLog        = "VcIssueLog"
+	Topic_SetDidDocument      = "SetDidDocument"
+	Topic_SetTrustRootList    = "SetTrustRootList"
+	Topic_RevokeVc            = "RevokeVc"
+	Topic_AddBlackList        = "AddBlackList"
+	Topic_DeleteBlackList     = "DeleteBlackList"
+	Topic_AddTrustIssuer      = "AddTrustIssuer"
+	Topic_DeleteTrustIssuer   = "DeleteTrustIssuer"
+	Topic_Delegate            = "Delegate"
+	Topic_RevokeDelegate      = "RevokeDelegate"
+	Topic_SetVcTemplate       = "SetVcTemplate"
+	Topic_VcIssueLog          = "VcIssueLog"
+	Topic_SetAdminCouncil     = "SetAdminCouncil"
+	Topic_Propose             = "Propose"
+	Topic_Approve             = "Approve"
+	Topic_Reject              = "Reject"
+	Topic_ProposalStatus      = "ProposalStatus"
+	Topic_GrantRole           = "GrantRole"
+	Topic_RevokeRole          = "RevokeRole"
+	Topic_ProposeAdmin        = "ProposeAdmin"
+	Topic_AcceptAdmin         = "AcceptAdmin"
+	Topic_CancelAdminTransfer = "CancelAdminTransfer"
+	Topic_Pause               = "Pause"
+	Topic_Unpause             = "Unpause"
+	Topic_SetConfig           = "SetConfig"
+	Topic_Migrate             = "Migrate"
+	Topic_SetVcTemplateStatus = "SetVcTemplateStatus"
+	Topic_SetVcTemplateCompat = "SetVcTemplateCompatibility"
+	Topic_DelegateCapability  = "DelegateCapability"
+	Topic_DeactivateDid       = "DeactivateDid"
 )
 
 // CMDID 长安链DID
@@ -49,7 +67,8 @@ type CMDID interface {
 	// RevokeVc 撤销vc,撤销后的vc vp不能再被验证
 	RevokeVc(vcID string) error
 	// GetRevokedVcList 获取撤销vc列表
-	GetRevokedVcList(vcIDSearch string, start int, count int) ([]string, error)
+	// @param cursor 上一页返回的游标，为空表示第一页
+	GetRevokedVcList(vcIDSearch string, cursor string, count int) (*Page[string], error)
 	// EmitRevokeVcEvent 发送撤销vc事件
 	EmitRevokeVcEvent(vcID string)
 }
@@ -58,14 +77,17 @@ type CMDIDOption interface {
 	// UpdateDidDocument 更新DID文档
 	UpdateDidDocument(didDocument string) error
 
-	// AddBlackList 添加黑名单
-	AddBlackList(dids []string) error
+	// AddBlackList 添加黑名单，条目可以是DID、验证方法ID、公钥或地址
+	// @param reason 原因
+	// @param evidence 证据引用
+	// @param expiration 自动失效时间，unix时间戳，0表示永久
+	AddBlackList(dids []string, reason string, evidence string, expiration int64) error
 	// DeleteBlackList 删除黑名单
 	DeleteBlackList(dids []string) error
 	// GetBlackList 获取黑名单
-	GetBlackList(didSearch string, start int, count int) ([]string, error)
+	GetBlackList(didSearch string, cursor string, count int) (*Page[*BlackListEntry], error)
 	// EmitAddBlackListEvent 发送添加黑名单事件
-	EmitAddBlackListEvent(dids []string)
+	EmitAddBlackListEvent(entries []*BlackListEntry)
 	// EmitDeleteBlackListEvent 发送删除黑名单事件
 	EmitDeleteBlackListEvent(dids []string)
 
@@ -81,7 +103,7 @@ type CMDIDOption interface {
 	// DeleteTrustIssuer 删除信任的发行者
 	DeleteTrustIssuer(dids []string) error
 	// GetTrustIssuer 获取信任的发行者
-	GetTrustIssuer(didSearch string, start int, count int) ([]string, error)
+	GetTrustIssuer(didSearch string, cursor string, count int) (*Page[string], error)
 	// EmitAddTrustIssuerEvent 发送添加信任的发行者事件
 	EmitAddTrustIssuerEvent(dids []string)
 	// EmitDeleteTrustIssuerEvent 发送删除信任的发行者事件
@@ -100,25 +122,30 @@ type CMDIDOption interface {
 	// EmitRevokeDelegateEvent 发送撤销授权事件
 	EmitRevokeDelegateEvent(delegatorDid string, delegateeDid string, resource string, action string)
 	// GetDelegateList 查询授权列表
-	GetDelegateList(delegatorDid, delegateeDid string, resource string, action string, start int, count int) ([]*DelegateInfo, error)
+	GetDelegateList(delegatorDid, delegateeDid string, resource string, action string, cursor string, count int) (*Page[*DelegateInfo], error)
 
-	// SetVcTemplate 设置vc模板
+	// SetVcTemplate 发布vc模板，已发布的版本不可修改；语义化版本按MAJOR.MINOR.PATCH保存，1.2和1.2.0是同一个版本
 	SetVcTemplate(id string, name string, vcType string, version string, template string) error
 	// GetVcTemplate 获取vc模板
 	GetVcTemplate(id, version string) (*VcTemplate, error)
 	// GetVcTemplateList 获取vc模板列表
-	GetVcTemplateList(nameSearch string, start int, count int) ([]*VcTemplate, error)
+	GetVcTemplateList(nameSearch string, cursor string, count int) (*Page[*VcTemplate], error)
 	// EmitSetVcTemplateEvent 发送设置vc模板事件
 	EmitSetVcTemplateEvent(templateID string, templateName string, vcType string, version string, vcTemplate string)
 
-	// VcIssueLog 记录vc发行日志
+	// VcIssueLog 记录vc发行日志，交易发送者必须是发行者或者得到发行者"issue"授权
 	// @param issuer 必填，发行者DID
 	// @param did 必填，vc持有者DID
 	// @param templateID 选填，vc模板ID
+	// @param templateVersion 选填，vc引用的模板版本或者^1.2、~1.2等范围，该版本必须可以签发；为空时模板有可签发的版本即可
 	// @param vcID 必填，vcID或者vc hash
-	VcIssueLog(issuer string, did string, templateID string, vcID string) error
+	// @param vcHash 选填，vc去掉proof后按JCS（RFC 8785）规范化JSON的十六进制哈希，key顺序和空白不影响哈希
+	// @param hashAlgorithm 选填，vcHash的哈希算法，默认sha256
+	// @param expiration 选填，vc过期时间（unix秒），0表示不记录
+	VcIssueLog(issuer string, did string, templateID string, templateVersion string, vcID string, vcHash string,
+		hashAlgorithm string, expiration int64) error
 	// GetVcIssueLogs 获取vc发行日志
-	GetVcIssueLogs(issuer string, did string, templateID string, start int, count int) ([]*VcIssueLog, error)
+	GetVcIssueLogs(issuer string, did string, templateID string, cursor string, count int) (*Page[*VcIssueLog], error)
 	// GetVcIssuers 根据持证人DID获取vc发行者DID列表
 	GetVcIssuers(did string) (issuerDid []string, err error)
 	// EmitVcIssueLogEvent 发送vc发行日志事件
@@ -133,10 +160,44 @@ type VcIssueLog struct {
 	Did string `json:"did"`
 	// TemplateId vc模板ID
 	TemplateId string `json:"templateID"`
+	// TemplateVersion 签发时vc引用的模板版本，范围查询时为解析出的具体版本，为空表示未记录
+	TemplateVersion string `json:"templateVersion,omitempty"`
 	// VcID vcID或者vc hash
 	VcID string `json:"vcID"`
 	// IssueTime 发行上链时间
 	IssueTime int64 `json:"issueTime"`
+	// VcHash vc去掉proof后JCS（RFC 8785）规范化JSON的十六进制哈希
+	VcHash string `json:"vcHash,omitempty"`
+	// HashAlgorithm VcHash的哈希算法
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+	// Expiration vc过期时间（unix秒），0表示未记录
+	Expiration int64 `json:"expiration,omitempty"`
+}
+
+// vc状态
+const (
+	// VcStatusActive 有效
+	VcStatusActive = "active"
+	// VcStatusRevoked 已撤销
+	VcStatusRevoked = "revoked"
+	// VcStatusExpired 已过期
+	VcStatusExpired = "expired"
+)
+
+// HolderCredential 持有人的vc及其当前状态
+type HolderCredential struct {
+	// VcID vcID或者vc hash
+	VcID string `json:"vcID"`
+	// Issuer 发行者DID
+	Issuer string `json:"issuer"`
+	// TemplateId vc模板ID
+	TemplateId string `json:"templateID"`
+	// IssueTime 最近一次发行上链时间
+	IssueTime int64 `json:"issueTime"`
+	// Expiration vc过期时间（unix秒），0表示未记录
+	Expiration int64 `json:"expiration"`
+	// Status vc状态，active、revoked、expired
+	Status string `json:"status"`
 }
 
 // VcTemplate vc模板
@@ -149,8 +210,100 @@ type VcTemplate struct {
 	Version string `json:"version"`
 	// VcType vc类型
 	VcType string `json:"vcType"`
-	// Template 模板内容
+	// Template 模板内容，校验credentialSubject的JSON Schema
 	Template string `json:"template"`
+	// CredentialSchema 校验整个vc（不含proof）的JSON Schema，可选
+	CredentialSchema string `json:"credentialSchema,omitempty"`
+	// Owner 模板所有者DID，可以管理该模板ID下的所有版本
+	Owner string `json:"owner,omitempty"`
+	// Status 模板状态，draft、active、deprecated、retired，为空表示active
+	Status string `json:"status,omitempty"`
+	// CreateTime 模板版本创建上链时间
+	CreateTime int64 `json:"createTime,omitempty"`
+	// Metadata 模板的展示信息和验证约束，可选
+	Metadata *TemplateMetadata `json:"metadata,omitempty"`
+}
+
+// TemplateMetadata vc模板的展示信息和验证约束
+type TemplateMetadata struct {
+	// Display 按语言区域（例如zh-CN、en-US）的展示信息
+	Display map[string]*TemplateDisplay `json:"display,omitempty"`
+	// Context vc的@context中必须包含的JSON-LD context URL
+	Context string `json:"context,omitempty"`
+	// ContextHash Context文档的sha256十六进制哈希，供链下校验context内容
+	ContextHash string `json:"contextHash,omitempty"`
+	// AllowedIssuers 允许签发该模板vc的发行者DID，为空表示不限制
+	AllowedIssuers []string `json:"allowedIssuers,omitempty"`
+	// DefaultValidity 建议的vc有效期（秒）
+	DefaultValidity int64 `json:"defaultValidity,omitempty"`
+	// MaxValidity vc有效期（expirationDate-issuanceDate）上限（秒），0表示不限制
+	MaxValidity int64 `json:"maxValidity,omitempty"`
+	// RequiredEvidence vc的evidence中必须包含的type
+	RequiredEvidence []string `json:"requiredEvidence,omitempty"`
+	// RequiredTermsOfUse vc的termsOfUse中必须包含的type
+	RequiredTermsOfUse []string `json:"requiredTermsOfUse,omitempty"`
+}
+
+// TemplateDisplay vc模板某个语言区域的展示信息
+type TemplateDisplay struct {
+	// Title 标题
+	Title string `json:"title,omitempty"`
+	// Description 描述
+	Description string `json:"description,omitempty"`
+	// FieldLabels credentialSubject字段名到展示名称的映射
+	FieldLabels map[string]string `json:"fieldLabels,omitempty"`
+	// BackgroundColor 背景颜色，例如#1E88E5
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	// TextColor 文字颜色
+	TextColor string `json:"textColor,omitempty"`
+	// LogoUrl 图标URL
+	LogoUrl string `json:"logoUrl,omitempty"`
+}
+
+// TemplateCompatibility vc模板兼容声明，引用Range内版本的vc可以按Version的模板验证
+type TemplateCompatibility struct {
+	// Range 版本范围，例如^1.2、~1.2.0
+	Range string `json:"range"`
+	// Version 用于验证的兼容模板版本
+	Version string `json:"version"`
+}
+
+// vc模板状态
+const (
+	// TemplateStatusDraft 草稿，可以修改，不能用于签发和验证
+	TemplateStatusDraft = "draft"
+	// TemplateStatusActive 已发布，内容不可修改
+	TemplateStatusActive = "active"
+	// TemplateStatusDeprecated 不推荐使用，仍然可以签发和验证
+	TemplateStatusDeprecated = "deprecated"
+	// TemplateStatusRetired 已停用，不能再签发，已签发的vc仍然可以验证
+	TemplateStatusRetired = "retired"
+)
+
+// Page 分页查询结果
+type Page[T any] struct {
+	// Items 当前页的数据
+	Items []T `json:"items"`
+	// NextCursor 下一页的游标，为空表示没有下一页
+	NextCursor string `json:"nextCursor"`
+	// Total 符合条件的总数，只有合约配置了pageTotal时才统计
+	Total *int `json:"total,omitempty"`
+}
+
+// BlackListEntry 黑名单记录
+type BlackListEntry struct {
+	// Did 黑名单条目，可以是DID、验证方法ID、公钥或地址
+	Did string `json:"did"`
+	// Reason 加入黑名单的原因
+	Reason string `json:"reason,omitempty"`
+	// Evidence 证据引用，如证据哈希或链接
+	Evidence string `json:"evidence,omitempty"`
+	// Creator 添加黑名单的管理员DID
+	Creator string `json:"creator,omitempty"`
+	// CreateTime 添加时间
+	CreateTime int64 `json:"createTime"`
+	// Expiration 自动失效时间，unix时间戳，0表示永久
+	Expiration int64 `json:"expiration"`
 }
 
 // DelegateInfo 授权信息
@@ -167,4 +320,10 @@ type DelegateInfo struct {
 	StartTime int64 `json:"startTime"`
 	// Expiration 授权结束时间
 	Expiration int64 `json:"expiration"`
+	// CapabilityDelegation 被授权者是否可以将该授权再委托给他人
+	CapabilityDelegation bool `json:"capabilityDelegation,omitempty"`
+	// ParentDelegator 再委托时授予DelegatorDid该授权的上一级DID，为空表示授权链的起点
+	ParentDelegator string `json:"parentDelegator,omitempty"`
+	// RootDelegator 授权链起点的授权者DID
+	RootDelegator string `json:"rootDelegator,omitempty"`
 }
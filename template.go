@@ -0,0 +1,497 @@
+package main
+
+import (
+	"crypto/sha256"
+	"did/standard"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"chainmaker.org/chainmaker/contract-sdk-go/v2/sdk"
+	"github.com/xeipuuv/gojsonschema"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	templateStatuses = []string{standard.TemplateStatusDraft, standard.TemplateStatusActive,
+		standard.TemplateStatusDeprecated, standard.TemplateStatusRetired}
+	// templateTransitions 模板状态允许的转换，停用后不能再恢复
+	templateTransitions = map[string][]string{
+		standard.TemplateStatusDraft:      {standard.TemplateStatusActive, standard.TemplateStatusRetired},
+		standard.TemplateStatusActive:     {standard.TemplateStatusDeprecated, standard.TemplateStatusRetired},
+		standard.TemplateStatusDeprecated: {standard.TemplateStatusActive, standard.TemplateStatusRetired},
+	}
+	errTemplateImmutable = errors.New("vc template version is published and immutable")
+)
+
+// templateStatus 获取模板状态，旧版本合约创建的模板没有状态，视为已发布
+func templateStatus(vcTemplate *standard.VcTemplate) string {
+	if len(vcTemplate.Status) == 0 {
+		return standard.TemplateStatusActive
+	}
+	return vcTemplate.Status
+}
+
+// SetVcTemplateDraft 保存VC模板草稿，草稿可以反复修改，通过SetVcTemplate或者SetVcTemplateStatus发布
+// @param credentialSchema 选填，校验整个vc的JSON Schema
+// @param metadata 选填，TemplateMetadata的JSON
+func (e *DidContract) SetVcTemplateDraft(id string, name string, vcType, version string, template string,
+	credentialSchema string, metadata string) error {
+	return e.saveVcTemplate(id, name, vcType, version, template, credentialSchema, metadata,
+		standard.TemplateStatusDraft)
+}
+
+// SetVcTemplateWithMetadata 发布带有整个vc的Schema、展示信息和验证约束的VC模板
+// @param credentialSchema 选填，校验整个vc的JSON Schema
+// @param metadata 选填，TemplateMetadata的JSON
+func (e *DidContract) SetVcTemplateWithMetadata(id string, name string, vcType, version string, template string,
+	credentialSchema string, metadata string) error {
+	return e.saveVcTemplate(id, name, vcType, version, template, credentialSchema, metadata,
+		standard.TemplateStatusActive)
+}
+
+// saveVcTemplate 保存VC模板，只有草稿可以覆盖
+// 语义化版本按MAJOR.MINOR.PATCH[-PRERELEASE]规范化后保存，1.2、v1.2和1.2.0是同一个版本；
+// 旧合约创建的模板如果已有非语义化版本，可以继续发布非语义化版本，这些版本只能按原值精确查询，
+// 不参与latest、^、~等范围查询和兼容声明；新模板必须使用语义化版本
+func (e *DidContract) saveVcTemplate(id string, name string, vcType, version string, template string,
+	credentialSchema string, metadata string, status string) error {
+	if len(id) == 0 || len(version) == 0 {
+		return errors.New("vc template id or version is empty")
+	}
+	templateMetadata, err := parseTemplateMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	owner, err := e.checkTemplatePermission(id)
+	if err != nil {
+		return err
+	}
+	version, err = e.checkTemplateVersion(id, version)
+	if err != nil {
+		return err
+	}
+	err = checkTemplateValid(template)
+	if err != nil {
+		return errors.New("invalid vc template: " + err.Error())
+	}
+	if len(credentialSchema) != 0 {
+		if err = checkTemplateValid(credentialSchema); err != nil {
+			return errors.New("invalid vc credential schema: " + err.Error())
+		}
+	}
+	old, err := e.dal.getVcTemplate(id, version)
+	if err == nil && templateStatus(old) != standard.TemplateStatusDraft {
+		return errTemplateImmutable
+	}
+	myTime, err := getTxTime()
+	if err != nil {
+		return err
+	}
+	err = e.dal.putVcTemplate(&standard.VcTemplate{
+		Id:               id,
+		Name:             name,
+		VcType:           vcType,
+		Version:          version,
+		Template:         template,
+		CredentialSchema: credentialSchema,
+		Owner:            owner,
+		Status:           status,
+		CreateTime:       myTime,
+		Metadata:         templateMetadata,
+	})
+	if err != nil {
+		return err
+	}
+	if len(owner) != 0 {
+		if err = e.dal.putTemplateOwner(id, owner); err != nil {
+			return err
+		}
+	}
+	e.EmitSetVcTemplateEvent(id, name, vcType, version, template)
+	return nil
+}
+
+// checkTemplateVersion 返回规范化后的模板版本，同一版本的其他写法已经存在时报错，防止重复版本
+func (e *DidContract) checkTemplateVersion(id string, version string) (string, error) {
+	templates, err := e.getVcTemplatesById(id)
+	if err != nil {
+		return "", err
+	}
+	v, err := parseSemVersion(version)
+	if err != nil {
+		for _, vcTemplate := range templates {
+			if _, err1 := parseSemVersion(vcTemplate.Version); err1 != nil {
+				return version, nil
+			}
+		}
+		return "", errors.New("invalid vc template version, need semantic version: " + version)
+	}
+	for _, vcTemplate := range templates {
+		old, err1 := parseSemVersion(vcTemplate.Version)
+		if err1 == nil && old.compare(v) == 0 && vcTemplate.Version != v.String() {
+			return "", errors.New("vc template version " + version + " already exists as " + vcTemplate.Version)
+		}
+	}
+	return v.String(), nil
+}
+
+// SetVcTemplateStatus 修改VC模板版本的状态
+func (e *DidContract) SetVcTemplateStatus(id string, version string, status string) error {
+	if !isInList(status, templateStatuses) {
+		return errors.New("invalid vc template status: " + status)
+	}
+	if _, err := e.checkTemplatePermission(id); err != nil {
+		return err
+	}
+	vcTemplate, err := e.dal.getVcTemplate(id, version)
+	if err != nil {
+		return err
+	}
+	if !isInList(status, templateTransitions[templateStatus(vcTemplate)]) {
+		return errors.New("vc template status can not change from " + templateStatus(vcTemplate) + " to " + status)
+	}
+	vcTemplate.Status = status
+	err = e.dal.putVcTemplate(vcTemplate)
+	if err != nil {
+		return err
+	}
+	e.EmitSetVcTemplateStatusEvent(id, version, status)
+	return nil
+}
+
+// EmitSetVcTemplateStatusEvent 发送修改VC模板状态事件
+func (e *DidContract) EmitSetVcTemplateStatusEvent(id string, version string, status string) {
+	sdk.Instance.EmitEvent(standard.Topic_SetVcTemplateStatus, []string{id, version, status})
+}
+
+// checkTemplatePermission 检查sender是否可以管理模板，返回模板所有者
+// 所有者可以管理自己的模板，信任发行者可以创建新模板并成为所有者，模板管理员和管理员可以管理所有模板
+func (e *DidContract) checkTemplatePermission(id string) (string, error) {
+	senderDid, err := e.getSenderDid()
+	if err != nil {
+		return "", err
+	}
+	owner, err := e.dal.getTemplateOwner(id)
+	if err != nil {
+		return "", err
+	}
+	//管理员需要通过提案操作，不因为是所有者而跳过
+	if len(owner) != 0 {
+		if owner == senderDid && !e.isAdmin() {
+			return owner, nil
+		}
+		return owner, e.checkPermission(RoleTemplateManager, true)
+	}
+	templates, err := e.getVcTemplatesById(id)
+	if err != nil {
+		return "", err
+	}
+	//旧版本合约创建的模板没有所有者，只能由模板管理员和管理员管理
+	if len(templates) != 0 {
+		return "", e.checkPermission(RoleTemplateManager, true)
+	}
+	if _, err = e.dal.getTrustIssuer(senderDid); err == nil && !e.isAdmin() {
+		return senderDid, nil
+	}
+	return senderDid, e.checkPermission(RoleTemplateManager, true)
+}
+
+// getVcTemplatesById 获取模板ID下的所有版本
+func (e *DidContract) getVcTemplatesById(id string) ([]*standard.VcTemplate, error) {
+	templates, err := e.dal.getVcTemplateById(id)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*standard.VcTemplate, 0, len(templates))
+	for _, vcTemplate := range templates {
+		if vcTemplate.Id == id {
+			result = append(result, vcTemplate)
+		}
+	}
+	return result, nil
+}
+
+// checkTemplateIssuable 检查模板版本是否可以签发，草稿和已停用的版本不能签发，返回解析出的具体版本
+// version为范围查询时取范围内最高的可签发版本；version为空时模板有可签发的版本即可，返回空
+func (e *DidContract) checkTemplateIssuable(id string, version string) (string, error) {
+	if len(version) != 0 {
+		vcTemplate, err := e.resolveVcTemplate(id, version)
+		if err != nil {
+			return "", err
+		}
+		if !isTemplateIssuable(vcTemplate) {
+			return "", errors.New("vc template " + id + " version " + vcTemplate.Version + " is not issuable")
+		}
+		return vcTemplate.Version, nil
+	}
+	templates, err := e.getVcTemplatesById(id)
+	if err != nil {
+		return "", err
+	}
+	if len(templates) == 0 {
+		return "", errTemplateNotFound
+	}
+	for _, vcTemplate := range templates {
+		if isTemplateIssuable(vcTemplate) {
+			return "", nil
+		}
+	}
+	return "", errors.New("vc template " + id + " has no issuable version")
+}
+
+// isTemplateIssuable 只有启用和已弃用的版本可以签发
+func isTemplateIssuable(vcTemplate *standard.VcTemplate) bool {
+	status := templateStatus(vcTemplate)
+	return status == standard.TemplateStatusActive || status == standard.TemplateStatusDeprecated
+}
+
+// normalizeTemplateName 规范化模板名称用于搜索：NFKC兼容等价（全角转半角等）、转小写、合并空白
+func normalizeTemplateName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFKC.String(name))), " ")
+}
+
+// GetVcTemplatesByType 根据vc类型获取VC模板列表
+func (e *DidContract) GetVcTemplatesByType(vcType string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	if len(vcType) == 0 {
+		return nil, errors.New("vcType is empty")
+	}
+	return e.dal.searchVcTemplateByIndex(keyTemplateByType, encodeKey(vcType)+".",
+		func(vcTemplate *standard.VcTemplate) bool { return vcTemplate.VcType == vcType }, cursor, count)
+}
+
+// GetVcTemplatesByOwner 根据所有者DID获取VC模板列表
+func (e *DidContract) GetVcTemplatesByOwner(owner string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	if len(owner) == 0 {
+		return nil, errors.New("owner is empty")
+	}
+	return e.dal.searchVcTemplateByIndex(keyTemplateByOwner, encodeKey(owner)+".",
+		func(vcTemplate *standard.VcTemplate) bool { return vcTemplate.Owner == owner }, cursor, count)
+}
+
+// GetVcTemplatesByIdPrefix 根据模板ID前缀获取VC模板列表
+func (e *DidContract) GetVcTemplatesByIdPrefix(idPrefix string, cursor string, count int) (
+	*standard.Page[*standard.VcTemplate], error) {
+	return e.dal.searchVcTemplateByIdPrefix(idPrefix, cursor, count)
+}
+
+// GetLatestVcTemplate 获取模板ID下语义化版本最高的可签发版本，草稿和已停用的版本除外
+func (e *DidContract) GetLatestVcTemplate(id string) (*standard.VcTemplate, error) {
+	return e.resolveVcTemplate(id, versionLatest)
+}
+
+// resolveVcTemplate 获取模板，version可以是具体版本，也可以是latest、^1.2、~1.2等查询，
+// 查询时返回范围内版本最高的可签发版本
+func (e *DidContract) resolveVcTemplate(id string, version string) (*standard.VcTemplate, error) {
+	if !isVersionQuery(version) {
+		return e.dal.getVcTemplate(id, version)
+	}
+	r, err := parseVersionRange(version)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := e.getVcTemplatesById(id)
+	if err != nil {
+		return nil, err
+	}
+	var latest *standard.VcTemplate
+	var latestVersion *semVersion
+	for _, vcTemplate := range templates {
+		if !isTemplateIssuable(vcTemplate) {
+			continue
+		}
+		v, err1 := parseSemVersion(vcTemplate.Version)
+		if err1 != nil || !r.contains(v) {
+			continue
+		}
+		if latest == nil || v.compare(latestVersion) > 0 {
+			latest, latestVersion = vcTemplate, v
+		}
+	}
+	if latest == nil {
+		return nil, errTemplateNotFound
+	}
+	return latest, nil
+}
+
+// SetVcTemplateCompatibility 声明引用versionRange内版本的VC可以按version的模板验证，同一范围重复声明会覆盖
+func (e *DidContract) SetVcTemplateCompatibility(id string, versionRange string, version string) error {
+	if _, err := e.checkTemplatePermission(id); err != nil {
+		return err
+	}
+	r, err := parseVersionRange(versionRange)
+	if err != nil {
+		return err
+	}
+	v, err := parseSemVersion(version)
+	if err != nil {
+		return err
+	}
+	if !r.contains(v) {
+		return errors.New("compatible version " + version + " is not in range " + versionRange)
+	}
+	vcTemplate, err := e.dal.getVcTemplate(id, version)
+	if err != nil {
+		return err
+	}
+	status := templateStatus(vcTemplate)
+	if status != standard.TemplateStatusActive && status != standard.TemplateStatusDeprecated {
+		return errors.New("compatible vc template version must be active or deprecated")
+	}
+	compats, err := e.dal.getTemplateCompatibility(id)
+	if err != nil {
+		return err
+	}
+	compat := &standard.TemplateCompatibility{Range: versionRange, Version: version}
+	replaced := false
+	for i, c := range compats {
+		if c.Range == versionRange {
+			compats[i], replaced = compat, true
+		}
+	}
+	if !replaced {
+		compats = append(compats, compat)
+	}
+	err = e.dal.putTemplateCompatibility(id, compats)
+	if err != nil {
+		return err
+	}
+	e.EmitSetVcTemplateCompatibilityEvent(id, versionRange, version)
+	return nil
+}
+
+// GetVcTemplateCompatibility 获取模板的兼容声明
+func (e *DidContract) GetVcTemplateCompatibility(id string) ([]*standard.TemplateCompatibility, error) {
+	return e.dal.getTemplateCompatibility(id)
+}
+
+// EmitSetVcTemplateCompatibilityEvent 发送设置模板兼容声明事件
+func (e *DidContract) EmitSetVcTemplateCompatibilityEvent(id string, versionRange string, version string) {
+	sdk.Instance.EmitEvent(standard.Topic_SetVcTemplateCompat, []string{id, versionRange, version})
+}
+
+// resolveVcTemplateForVc 获取验证VC所用的模板：优先使用VC引用的可签发版本，
+// 引用的版本不存在或者已停用时使用兼容声明的版本，都没有时已停用的版本仍然可以验证之前签发的VC
+func (e *DidContract) resolveVcTemplateForVc(id string, version string) (*standard.VcTemplate, error) {
+	vcTemplate, err := e.dal.getVcTemplate(id, version)
+	if err == nil && templateStatus(vcTemplate) != standard.TemplateStatusRetired {
+		return vcTemplate, nil
+	}
+	v, err1 := parseSemVersion(version)
+	if err1 != nil {
+		return vcTemplate, err
+	}
+	compats, err1 := e.dal.getTemplateCompatibility(id)
+	if err1 != nil {
+		return nil, err1
+	}
+	for _, compat := range compats {
+		r, err2 := parseVersionRange(compat.Range)
+		if err2 != nil || !r.contains(v) {
+			continue
+		}
+		compatTemplate, err2 := e.dal.getVcTemplate(id, compat.Version)
+		if err2 == nil && templateStatus(compatTemplate) != standard.TemplateStatusRetired {
+			return compatTemplate, nil
+		}
+	}
+	return vcTemplate, err
+}
+
+// parseTemplateMetadata 解析并检查模板metadata，为空时返回nil
+func parseTemplateMetadata(metadata string) (*standard.TemplateMetadata, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	var m standard.TemplateMetadata
+	decoder := json.NewDecoder(strings.NewReader(metadata))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&m); err != nil {
+		return nil, errors.New("invalid vc template metadata: " + err.Error())
+	}
+	if len(m.ContextHash) != 0 {
+		if len(m.Context) == 0 {
+			return nil, errors.New("vc template metadata contextHash without context")
+		}
+		hash, err := hex.DecodeString(m.ContextHash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, errors.New("vc template metadata contextHash must be sha256 hex")
+		}
+		m.ContextHash = strings.ToLower(m.ContextHash)
+	}
+	if m.DefaultValidity < 0 || m.MaxValidity < 0 {
+		return nil, errors.New("vc template metadata validity must not be negative")
+	}
+	if m.MaxValidity != 0 && m.DefaultValidity > m.MaxValidity {
+		return nil, errors.New("vc template metadata defaultValidity exceeds maxValidity")
+	}
+	return &m, nil
+}
+
+// checkVcTemplateMetadata 检查VC是否满足模板metadata中可以在链上校验的约束
+func checkVcTemplateMetadata(vc *VerifiableCredential, m *standard.TemplateMetadata) error {
+	if m == nil {
+		return nil
+	}
+	if len(m.Context) != 0 && !isInList(m.Context, vc.Context) {
+		return errors.New("vc @context must include " + m.Context)
+	}
+	if len(m.AllowedIssuers) != 0 && !isInList(vc.Issuer, m.AllowedIssuers) {
+		return errors.New("vc issuer is not allowed by template")
+	}
+	if m.MaxValidity != 0 {
+		issuanceDate, err := time.Parse(time.RFC3339, vc.IssuanceDate)
+		if err != nil {
+			return err
+		}
+		expirationDate, err := time.Parse(time.RFC3339, vc.ExpirationDate)
+		if err != nil {
+			return err
+		}
+		if expirationDate.Sub(issuanceDate) > time.Duration(m.MaxValidity)*time.Second {
+			return errors.New("vc validity exceeds template max validity")
+		}
+	}
+	evidenceTypes := entryTypes(vc.Evidence)
+	for _, t := range m.RequiredEvidence {
+		if !isInList(t, evidenceTypes) {
+			return errors.New("vc evidence " + t + " is required by template")
+		}
+	}
+	termsOfUseTypes := entryTypes(vc.TermsOfUse)
+	for _, t := range m.RequiredTermsOfUse {
+		if !isInList(t, termsOfUseTypes) {
+			return errors.New("vc termsOfUse " + t + " is required by template")
+		}
+	}
+	return nil
+}
+
+const (
+	schemaKindSubject    = "subject"
+	schemaKindCredential = "credential"
+)
+
+// schemaCache 按模板版本缓存已编译的JSON Schema，只在一次交易内使用
+type schemaCache map[string]*gojsonschema.Schema
+
+// load 获取模板版本的Schema，未编译过时编译并缓存
+func (c schemaCache) load(vcTemplate *standard.VcTemplate, kind string, schema string) (*gojsonschema.Schema, error) {
+	key := joinKey(vcTemplate.Id, vcTemplate.Version, kind)
+	if compiled, ok := c[key]; ok {
+		return compiled, nil
+	}
+	if len(schema) == 0 {
+		return nil, errors.New("vc template schema is empty")
+	}
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, err
+	}
+	c[key] = compiled
+	return compiled, nil
+}